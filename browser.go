@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// httpDisplay is a Display that lives in a web browser, for machines without a screen.
+// Each presented frame is encoded as a JPEG once and pushed to every open MJPEG stream,
+// and the page posts pointer events, keys and commands back as JSON.
+//
+//	GET  /              the viewer page
+//	GET  /stream.mjpeg  a multipart/x-mixed-replace stream of frames
+//	GET  /frame.jpg     the latest frame
+//	POST /input         one browserInput
+//
+// It never asks to close; stop the demo with ctrl-c.
+type httpDisplay struct {
+	// FrameInterval is the shortest time between two presented frames. Without a window there is
+	// no vsync to hold the main loop back, so this keeps it from rendering as fast as it can.
+	FrameInterval time.Duration
+
+	mutex       sync.Mutex
+	frame       []byte
+	subscribers map[chan []byte]bool
+	input       []InputEvent
+	lastPresent time.Time
+	server      *http.Server
+}
+
+// browserInput is what the viewer page posts to /input. Exactly one of the fields is set.
+type browserInput struct {
+	Pointer *browserPointer `json:"pointer,omitempty"`
+	Char    string          `json:"char,omitempty"`
+	Command string          `json:"command,omitempty"`
+}
+
+// browserPointer is a PointerEvent in frame pixels. Kind is move, down, up or scroll, and
+// Button is the DOM MouseEvent.button: 0 for the primary button and 2 for the secondary one.
+type browserPointer struct {
+	Kind   string  `json:"kind"`
+	Button int     `json:"button"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Scroll float64 `json:"scroll"`
+}
+
+const mjpegQuality = 90
+
+var browserPointerKinds = map[string]PointerEventKind{
+	"move":   PointerMove,
+	"down":   PointerDown,
+	"up":     PointerUp,
+	"scroll": PointerScroll,
+}
+
+func newHTTPDisplay(frameInterval time.Duration) *httpDisplay {
+	return &httpDisplay{
+		FrameInterval: frameInterval,
+		subscribers:   map[chan []byte]bool{},
+	}
+}
+
+// listenHTTPDisplay serves a new httpDisplay on address, such as ":8080". The listener is opened
+// before it returns so that a port that is already taken is reported right away.
+func listenHTTPDisplay(address string, frameInterval time.Duration) (*httpDisplay, error) {
+	display := newHTTPDisplay(frameInterval)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	display.server = &http.Server{Handler: display.Handler()}
+	go func() {
+		err := display.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Println(err)
+		}
+	}()
+	log.Printf("serving the demo at http://%s/\n", listener.Addr())
+	return display, nil
+}
+
+// Handler serves the viewer. It is separate from listening so it can be mounted on an httptest.Server.
+func (display *httpDisplay) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", display.serveIndex)
+	mux.HandleFunc("/stream.mjpeg", display.serveStream)
+	mux.HandleFunc("/frame.jpg", display.serveFrame)
+	mux.HandleFunc("/input", display.serveInput)
+	return mux
+}
+
+func (display *httpDisplay) Present(rgba *image.RGBA) error {
+	display.mutex.Lock()
+	wait := display.FrameInterval - time.Since(display.lastPresent)
+	display.mutex.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, rgba, &jpeg.Options{Quality: mjpegQuality})
+	if err != nil {
+		return err
+	}
+
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	display.lastPresent = time.Now()
+	display.frame = buffer.Bytes()
+	for subscriber := range display.subscribers {
+		// a slow client skips frames instead of holding up the main loop
+		select {
+		case <-subscriber:
+		default:
+		}
+		subscriber <- display.frame
+	}
+	return nil
+}
+
+func (display *httpDisplay) PollInput() []InputEvent {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	input := display.input
+	display.input = nil
+	return input
+}
+
+func (display *httpDisplay) ShouldClose() bool {
+	return false
+}
+
+func (display *httpDisplay) Close() error {
+	if display.server == nil {
+		return nil
+	}
+	return display.server.Close()
+}
+
+func (display *httpDisplay) serveIndex(response http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(response, request)
+		return
+	}
+	response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(response, browserPage)
+}
+
+func (display *httpDisplay) serveFrame(response http.ResponseWriter, request *http.Request) {
+	display.mutex.Lock()
+	frame := display.frame
+	display.mutex.Unlock()
+	if frame == nil {
+		http.Error(response, "no frame has been rendered yet", http.StatusServiceUnavailable)
+		return
+	}
+	response.Header().Set("Content-Type", "image/jpeg")
+	response.Header().Set("Cache-Control", "no-store")
+	response.Write(frame)
+}
+
+func (display *httpDisplay) serveStream(response http.ResponseWriter, request *http.Request) {
+	frames := make(chan []byte, 1)
+	display.mutex.Lock()
+	if display.frame != nil {
+		frames <- display.frame
+	}
+	display.subscribers[frames] = true
+	display.mutex.Unlock()
+	defer func() {
+		display.mutex.Lock()
+		delete(display.subscribers, frames)
+		display.mutex.Unlock()
+	}()
+
+	parts := multipart.NewWriter(response)
+	response.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+parts.Boundary())
+	response.Header().Set("Cache-Control", "no-store")
+	flusher, _ := response.(http.Flusher)
+	if flusher != nil {
+		// send the headers right away, the first frame may be a while
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case frame := <-frames:
+			part, err := parts.CreatePart(textproto.MIMEHeader{
+				"Content-Type":   {"image/jpeg"},
+				"Content-Length": {fmt.Sprint(len(frame))},
+			})
+			if err == nil {
+				_, err = part.Write(frame)
+			}
+			if err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func (display *httpDisplay) serveInput(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		response.Header().Set("Allow", http.MethodPost)
+		http.Error(response, "POST a JSON object to /input", http.StatusMethodNotAllowed)
+		return
+	}
+	var input browserInput
+	err := json.NewDecoder(request.Body).Decode(&input)
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	event, err := input.toInputEvent()
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	display.mutex.Lock()
+	display.input = append(display.input, event)
+	display.mutex.Unlock()
+	response.WriteHeader(http.StatusNoContent)
+}
+
+func (input browserInput) toInputEvent() (InputEvent, error) {
+	switch {
+	case input.Pointer != nil:
+		kind, has := browserPointerKinds[input.Pointer.Kind]
+		if !has {
+			return InputEvent{}, fmt.Errorf("unknown pointer kind '%s', expected move, down, up or scroll", input.Pointer.Kind)
+		}
+		event := PointerEvent{
+			Kind:   kind,
+			X:      input.Pointer.X,
+			Y:      input.Pointer.Y,
+			Radius: cornerGrabDistance,
+			Scroll: input.Pointer.Scroll,
+		}
+		switch input.Pointer.Button {
+		case 0:
+			event.Button = PrimaryButton
+		case 2:
+			event.Button = SecondaryButton
+		default:
+			if kind == PointerDown || kind == PointerUp {
+				return InputEvent{}, fmt.Errorf("unsupported button %d, expected 0 or 2", input.Pointer.Button)
+			}
+		}
+		return InputEvent{Kind: InputPointer, Pointer: event}, nil
+	case input.Char != "":
+		chars := []rune(input.Char)
+		if len(chars) != 1 {
+			return InputEvent{}, fmt.Errorf("char must be a single character, got '%s'", input.Char)
+		}
+		return InputEvent{Kind: InputChar, Char: chars[0]}, nil
+	case input.Command != "":
+		return InputEvent{Kind: InputCommand, Command: input.Command}, nil
+	}
+	return InputEvent{}, fmt.Errorf("expected one of pointer, char or command")
+}
+
+// browserPage shows the stream at the frame's own size, so offsetX and offsetY are frame pixels,
+// and forwards the same mouse and keyboard input the OpenGL window handles.
+const browserPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>hilbert test</title>
+<style>
+	body { background: #111; color: #ccc; font-family: monospace; }
+	img { display: block; cursor: crosshair; image-rendering: pixelated; }
+	input { width: 30em; }
+</style>
+</head>
+<body>
+<img id="frame" src="/stream.mjpeg" draggable="false" tabindex="0">
+<form id="command"><input name="command" placeholder="command, like: bits 16, cost 2, view reset, select none"></form>
+<script>
+	const frame = document.getElementById("frame");
+	const post = (input) => fetch("/input", { method: "POST", body: JSON.stringify(input) });
+	const pointer = (kind, event, scroll) => {
+		const scaleX = frame.naturalWidth / frame.clientWidth || 1;
+		const scaleY = frame.naturalHeight / frame.clientHeight || 1;
+		post({ pointer: { kind: kind, button: event.button, x: event.offsetX * scaleX, y: event.offsetY * scaleY, scroll: scroll || 0 } });
+	};
+
+	frame.addEventListener("mousemove", (event) => pointer("move", event));
+	frame.addEventListener("mousedown", (event) => { frame.focus(); pointer("down", event); event.preventDefault(); });
+	frame.addEventListener("mouseup", (event) => pointer("up", event));
+	frame.addEventListener("contextmenu", (event) => event.preventDefault());
+	frame.addEventListener("wheel", (event) => { pointer("scroll", event, -Math.sign(event.deltaY)); event.preventDefault(); });
+	frame.addEventListener("keydown", (event) => {
+		if (event.key.length == 1 && !event.ctrlKey && !event.metaKey) {
+			post({ char: event.key });
+			event.preventDefault();
+		}
+	});
+
+	const form = document.getElementById("command");
+	form.addEventListener("submit", (event) => {
+		event.preventDefault();
+		post({ command: form.command.value });
+		form.command.value = "";
+	});
+</script>
+</body>
+</html>
+`
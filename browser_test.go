@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/jpeg"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func postInput(t *testing.T, server *httptest.Server, body string) int {
+	t.Helper()
+	response, err := http.Post(server.URL+"/input", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestHTTPDisplayInput(t *testing.T) {
+	display := newHTTPDisplay(0)
+	server := httptest.NewServer(display.Handler())
+	defer server.Close()
+
+	bodies := []string{
+		`{"pointer": {"kind": "down", "button": 0, "x": 12.5, "y": 30}}`,
+		`{"pointer": {"kind": "up", "button": 2, "x": 1, "y": 2}}`,
+		`{"pointer": {"kind": "scroll", "x": 5, "y": 6, "scroll": -1}}`,
+		`{"char": "z"}`,
+		`{"command": "bits 16"}`,
+	}
+	for _, body := range bodies {
+		if status := postInput(t, server, body); status != http.StatusNoContent {
+			t.Errorf("POST %s: expected %d, got %d", body, http.StatusNoContent, status)
+		}
+	}
+
+	expected := []InputEvent{
+		{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerDown, Button: PrimaryButton, X: 12.5, Y: 30, Radius: cornerGrabDistance}},
+		{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerUp, Button: SecondaryButton, X: 1, Y: 2, Radius: cornerGrabDistance}},
+		{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerScroll, X: 5, Y: 6, Radius: cornerGrabDistance, Scroll: -1}},
+		{Kind: InputChar, Char: 'z'},
+		{Kind: InputCommand, Command: "bits 16"},
+	}
+	if input := display.PollInput(); !reflect.DeepEqual(input, expected) {
+		t.Errorf("expected %+v, got %+v", expected, input)
+	}
+	if input := display.PollInput(); len(input) != 0 {
+		t.Errorf("PollInput should hand over each event once, got %+v again", input)
+	}
+}
+
+func TestHTTPDisplayRejectsBadInput(t *testing.T) {
+	display := newHTTPDisplay(0)
+	server := httptest.NewServer(display.Handler())
+	defer server.Close()
+
+	bodies := []string{
+		`{"pointer": {"kind": "wiggle", "x": 1, "y": 1}}`,
+		`{"pointer": {"kind": "down", "button": 1, "x": 1, "y": 1}}`,
+		`{"char": "ab"}`,
+		`{}`,
+		`not json`,
+	}
+	for _, body := range bodies {
+		if status := postInput(t, server, body); status != http.StatusBadRequest {
+			t.Errorf("POST %s: expected %d, got %d", body, http.StatusBadRequest, status)
+		}
+	}
+	if input := display.PollInput(); len(input) != 0 {
+		t.Errorf("bad input should not be delivered, got %+v", input)
+	}
+}
+
+func TestHTTPDisplayFrame(t *testing.T) {
+	display := newHTTPDisplay(0)
+	server := httptest.NewServer(display.Handler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/frame.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("before the first frame, expected %d, got %d", http.StatusServiceUnavailable, response.StatusCode)
+	}
+
+	err = display.Present(image.NewRGBA(image.Rect(0, 0, 40, 30)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = http.Get(server.URL + "/frame.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected a JPEG, got %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	frame, err := jpeg.Decode(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Bounds().Dx() != 40 || frame.Bounds().Dy() != 30 {
+		t.Errorf("expected a 40x30 frame, got %v", frame.Bounds())
+	}
+}
+
+func TestHTTPDisplayStream(t *testing.T) {
+	display := newHTTPDisplay(0)
+	server := httptest.NewServer(display.Handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream.mjpeg", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	mediaType, mediaParams, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/x-mixed-replace" || mediaParams["boundary"] == "" {
+		t.Fatalf("expected multipart/x-mixed-replace with a boundary, got %s", response.Header.Get("Content-Type"))
+	}
+
+	// the stream is subscribed by the time the headers arrive, so this frame goes out on it
+	err = display.Present(image.NewRGBA(image.Rect(0, 0, 40, 30)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := multipart.NewReader(response.Body, mediaParams["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Header.Get("Content-Type") != "image/jpeg" {
+		t.Errorf("expected a JPEG part, got %s", part.Header.Get("Content-Type"))
+	}
+	frame, err := jpeg.Decode(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Bounds().Dx() != 40 || frame.Bounds().Dy() != 30 {
+		t.Errorf("expected a 40x30 frame, got %v", frame.Bounds())
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// Clock maps a frame number to the animation time in seconds. Everything that moves in the demo
+// is a function of that time, so a frame number plus a deterministic clock is enough to
+// reproduce any frame exactly.
+type Clock interface {
+	Seconds(frame int) float64
+}
+
+// WallClock ignores the frame number and follows the system clock, like the original demo.
+type WallClock struct{}
+
+func (WallClock) Seconds(frame int) float64 {
+	return float64(time.Now().UnixNano()) / float64(int64(time.Second))
+}
+
+// SteppedClock advances by a fixed 1/FPS timestep per frame, starting at Start.
+type SteppedClock struct {
+	Start float64
+	FPS   float64
+}
+
+func (clock SteppedClock) Seconds(frame int) float64 {
+	return clock.Start + float64(frame)/clock.FPS
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	spatial "git.sequentialread.com/forest/modular-spatial-index"
+)
+
+// the values the cost+/cost-, bits+/bits- and cycles+/cycles- commands step through
+var iopsCostParamSteps = []float32{0.05, 0.1, 0.2, 0.5, 1, 2, 4, 8, 16}
+var curveBitsSteps = []int{8, 16, 32, 64}
+var paletteCyclesSteps = []float32{1, 2, 5, 10, 20, 50, 100, 200}
+
+// keyBindings maps keyboard characters to commands, independent of the window system.
+var keyBindings = map[rune]string{
+	'[': "cost-",
+	']': "cost+",
+	'-': "bits-",
+	'=': "bits+",
+	'z': "zoom+",
+	'x': "zoom-",
+	'w': "pan up",
+	'a': "pan left",
+	's': "pan down",
+	'd': "pan right",
+	'0': "view reset",
+	'i': "rect up",
+	'j': "rect left",
+	'k': "rect down",
+	'l': "rect right",
+	'o': "rect grow",
+	'u': "rect shrink",
+	'r': "rect release",
+	'c': "color next",
+	'v': "cycle toggle",
+	'p': "palette next",
+	',': "cycles-",
+	'.': "cycles+",
+	'h': "hud toggle",
+	'g': "curve toggle",
+	't': "inspect toggle",
+	'm': "mapping next",
+	'n': "compare next",
+	'y': "plot toggle",
+}
+
+// how far one zoom+/zoom- command zooms, and how far one pan command moves relative to what is visible
+const zoomStep = 2
+const panStep = 0.25
+
+// how far (in frame pixels at the current zoom) one rect command moves the rectangle or its edges
+const rectStep = 4
+
+// applyCommand changes params according to a text command. The keyboard bindings, the command
+// flags and the commands typed on stdin all end up here:
+//
+//	cost+ / cost-     step iopsCostParam up or down
+//	cost <value>      set iopsCostParam
+//	bits+ / bits-     step the curve bit width up or down
+//	bits <value>      set the curve bit width
+//	zoom+ / zoom-     zoom the view in or out around its center
+//	pan <direction>   move the view up, down, left or right
+//	view reset        show the whole input range again
+//	view <x>,<y>,<zoom>  center the view on a world position with the given zoom
+//	select <n>|none   highlight the footprint of range n, like clicking it in the number line
+//	rect <direction>  move the rectangle up, down, left or right
+//	rect grow / rect shrink  push its edges out or pull them in
+//	rect release      hand the rectangle back to the animation, like a right click
+//	rect <x>,<y>,<width>,<height>  place the rectangle, in the same pixels as -rect
+//	query add <x>,<y>,<width>,<height>  query another rectangle in the same batch
+//	query clear       go back to querying only the main rectangle
+//	color curve|range|next  color cells by their position on the curve, or by the range reading them
+//	cycle on|off|toggle     show the ranges one at a time, dimming everything else
+//	palette <name>|next     color the curve with a built in palette, see paletteNames
+//	cycles+ / cycles-       step how many times the palette repeats along the curve up or down
+//	cycles <value>          set how many times the palette repeats along the curve
+//	hud on|off|toggle       draw the stats into the frame
+//	curve on|off|toggle     draw the curve as a line through its cells, for bit widths up to 16
+//	inspect on|off|toggle   show what is under the pointer
+//	plot on|off|toggle      chart the range count, oversampling and rectangle area over time
+//	mapping <curve>|next    order the cells by another curve, see curveNames
+//	compare <curve>|next|off  split the frame to show the same rectangles on a second curve
+//
+// The rect commands only work when params.Path is a RectEditor, as it is in the interactive demo.
+func applyCommand(params *FrameParams, command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch fields[0] {
+	case "cost+":
+		params.IOPSCostParam = stepFloat32(iopsCostParamSteps, params.IOPSCostParam, 1)
+	case "cost-":
+		params.IOPSCostParam = stepFloat32(iopsCostParamSteps, params.IOPSCostParam, -1)
+	case "cost":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: cost <value>")
+		}
+		value, err := strconv.ParseFloat(fields[1], 32)
+		if err != nil || value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return fmt.Errorf("iopsCostParam must be a positive number, got '%s'", fields[1])
+		}
+		params.IOPSCostParam = float32(value)
+	case "bits+", "bits-":
+		direction := 1
+		if fields[0] == "bits-" {
+			direction = -1
+		}
+		return setCurveBits(params, stepInt(curveBitsSteps, params.CurveBits, direction))
+	case "bits":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: bits <value>")
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("curve bits must be an integer, got '%s'", fields[1])
+		}
+		return setCurveBits(params, value)
+	case "zoom+", "zoom-":
+		factor := float64(zoomStep)
+		if fields[0] == "zoom-" {
+			factor = 1 / factor
+		}
+		half := float64(params.Size) / 2
+		params.View.ZoomAround(half, half, factor, params.Size)
+	case "pan":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: pan up|down|left|right")
+		}
+		switch fields[1] {
+		case "up":
+			params.View.Pan(0, -panStep)
+		case "down":
+			params.View.Pan(0, panStep)
+		case "left":
+			params.View.Pan(-panStep, 0)
+		case "right":
+			params.View.Pan(panStep, 0)
+		default:
+			return fmt.Errorf("usage: pan up|down|left|right")
+		}
+	case "view":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: view reset|<x>,<y>,<zoom>")
+		}
+		if fields[1] == "reset" {
+			params.View = DefaultViewport()
+			return nil
+		}
+		view, err := parseView(fields[1])
+		if err != nil {
+			return err
+		}
+		params.View = view
+	case "select":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: select <range index>|none")
+		}
+		if fields[1] == "none" {
+			params.SelectedRange = -1
+			return nil
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil || value < 0 {
+			return fmt.Errorf("range index must be a number from 0 up, got '%s'", fields[1])
+		}
+		params.SelectedRange = value
+	case "query":
+		if len(fields) == 2 && fields[1] == "clear" {
+			params.ExtraPaths = nil
+			return nil
+		}
+		if len(fields) != 3 || fields[1] != "add" {
+			return fmt.Errorf("usage: query add <x>,<y>,<width>,<height> | query clear")
+		}
+		rect, err := parseRect(fields[2])
+		if err != nil {
+			return err
+		}
+		if rect.ToQueryRect(params.Size).Clamp().Empty() {
+			return fmt.Errorf("query %s is empty or outside the frame", fields[2])
+		}
+		// copy, so params that were copied from these don't see the new query
+		extraPaths := append([]RectPath{}, params.ExtraPaths...)
+		params.ExtraPaths = append(extraPaths, FixedPath{Rect: rect})
+	case "color":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: color curve|range|next")
+		}
+		if fields[1] == "next" {
+			params.ColorMode = (params.ColorMode + 1) % ColorMode(len(colorModeNames))
+			return nil
+		}
+		for mode, name := range colorModeNames {
+			if name == fields[1] {
+				params.ColorMode = mode
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown color mode '%s', expected curve, range or next", fields[1])
+	case "cycle", "hud", "curve", "inspect", "plot":
+		setting := &params.CycleRanges
+		switch fields[0] {
+		case "hud":
+			setting = &params.HUD
+		case "curve":
+			setting = &params.CurveOverlay
+		case "inspect":
+			setting = &params.Inspect
+		case "plot":
+			setting = &params.Plot
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: %s on|off|toggle", fields[0])
+		}
+		switch fields[1] {
+		case "on":
+			*setting = true
+		case "off":
+			*setting = false
+		case "toggle":
+			*setting = !*setting
+		default:
+			return fmt.Errorf("usage: %s on|off|toggle", fields[0])
+		}
+	case "palette":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: palette %s|next", strings.Join(paletteNames, "|"))
+		}
+		if fields[1] == "next" {
+			params.Palette = nextPalette(params.Palette)
+			return nil
+		}
+		palette, has := palettes[fields[1]]
+		if !has {
+			return fmt.Errorf("unknown palette '%s', expected one of %s", fields[1], strings.Join(paletteNames, ", "))
+		}
+		params.Palette = palette
+	case "cycles+", "cycles-":
+		direction := 1
+		if fields[0] == "cycles-" {
+			direction = -1
+		}
+		params.PaletteCycles = float64(stepFloat32(paletteCyclesSteps, float32(params.PaletteCycles), direction))
+	case "cycles":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: cycles <value>")
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return fmt.Errorf("palette cycles must be a positive number, got '%s'", fields[1])
+		}
+		params.PaletteCycles = value
+	case "mapping":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: mapping %s|next", strings.Join(curveNames, "|"))
+		}
+		if fields[1] == "next" {
+			params.Curve = curveNames[(curveIndex(params.Curve)+1)%len(curveNames)]
+			return nil
+		}
+		if curveIndex(fields[1]) == -1 {
+			return fmt.Errorf("unknown curve '%s', expected one of %s", fields[1], strings.Join(curveNames, ", "))
+		}
+		params.Curve = fields[1]
+	case "compare":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: compare %s|next|off", strings.Join(curveNames, "|"))
+		}
+		switch fields[1] {
+		case "off":
+			params.CompareCurve = ""
+		case "next":
+			// after the last curve comes off, and after off the first curve
+			index := curveIndex(params.CompareCurve) + 1
+			params.CompareCurve = ""
+			if index < len(curveNames) {
+				params.CompareCurve = curveNames[index]
+			}
+		default:
+			if curveIndex(fields[1]) == -1 {
+				return fmt.Errorf("unknown curve '%s', expected one of %s", fields[1], strings.Join(curveNames, ", "))
+			}
+			params.CompareCurve = fields[1]
+		}
+	case "rect":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: rect up|down|left|right|grow|shrink|release|<x>,<y>,<width>,<height>")
+		}
+		editor, isEditor := params.Path.(*RectEditor)
+		if !isEditor {
+			return fmt.Errorf("the rectangle can only be edited in the interactive demo")
+		}
+		return applyRectCommand(editor, params, fields[1])
+	default:
+		return fmt.Errorf("unknown command '%s'", fields[0])
+	}
+	return nil
+}
+
+func applyRectCommand(editor *RectEditor, params *FrameParams, argument string) error {
+	step := rectStep / float64(params.Size) / params.View.Zoom
+	switch argument {
+	case "up":
+		editor.Nudge(0, -step, 0)
+	case "down":
+		editor.Nudge(0, step, 0)
+	case "left":
+		editor.Nudge(-step, 0, 0)
+	case "right":
+		editor.Nudge(step, 0, 0)
+	case "grow":
+		editor.Nudge(0, 0, step)
+	case "shrink":
+		editor.Nudge(0, 0, -step)
+	case "release":
+		editor.Release()
+	default:
+		rect, err := parseRect(argument)
+		if err != nil {
+			return err
+		}
+		queryRect := rect.ToQueryRect(params.Size).Clamp()
+		if queryRect.Empty() {
+			return fmt.Errorf("rect %s is outside the frame", argument)
+		}
+		editor.Rect = queryRect
+		editor.Edited = true
+	}
+	return nil
+}
+
+// parseView parses "x,y,zoom", where x,y is the world position the view is centered on.
+func parseView(value string) (Viewport, error) {
+	numbers, err := parseFloats(value, 3, "x,y,zoom")
+	if err != nil {
+		return Viewport{}, err
+	}
+	if numbers[2] < 1 {
+		return Viewport{}, fmt.Errorf("zoom must be at least 1, got %g", numbers[2])
+	}
+	view := Viewport{CenterX: numbers[0], CenterY: numbers[1], Zoom: math.Min(maxZoom, numbers[2])}
+	view.clamp()
+	return view, nil
+}
+
+// setCurveBits only accepts bit widths the library can build an index for.
+func setCurveBits(params *FrameParams, curveBits int) error {
+	_, err := spatial.NewSpatialIndex2D(curveBits)
+	if err != nil {
+		return fmt.Errorf("can't use %d curve bits: %s", curveBits, err)
+	}
+	params.CurveBits = curveBits
+	return nil
+}
+
+// stepFloat32 returns the next value in steps above (direction 1) or below (direction -1) the
+// current value, or the current value if there is nothing further in that direction.
+func stepFloat32(steps []float32, current float32, direction int) float32 {
+	if direction > 0 {
+		for _, step := range steps {
+			if step > current {
+				return step
+			}
+		}
+	} else {
+		for i := len(steps) - 1; i >= 0; i-- {
+			if steps[i] < current {
+				return steps[i]
+			}
+		}
+	}
+	return current
+}
+
+func stepInt(steps []int, current int, direction int) int {
+	floatSteps := make([]float32, len(steps))
+	for i, step := range steps {
+		floatSteps[i] = float32(step)
+	}
+	return int(stepFloat32(floatSteps, float32(current), direction))
+}
+
+// readCommands sends each line of input to the commands channel until input runs out.
+func readCommands(input io.Reader, commands chan<- string) {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		commands <- scanner.Text()
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("stopped reading commands: %s\n", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyCommandRejectsBadValues(t *testing.T) {
+	commands := []string{
+		"cost nan",
+		"cost inf",
+		"cost -1",
+		"cycles inf",
+		"cycles -Inf",
+		"cycles nan",
+		"cycles 0",
+		"view 0.5,0.5,nan",
+		"view inf,0.5,2",
+		"query add 10,10,10,+Inf",
+		"query add 100,100,-20,10",
+		"query add 10,10,0,10",
+		"query add 500,500,10,10",
+	}
+	for _, command := range commands {
+		params := testParams()
+		before := testParams()
+		if err := applyCommand(&params, command); err == nil {
+			t.Errorf("%s: expected an error", command)
+		}
+		if !reflect.DeepEqual(params, before) {
+			t.Errorf("%s: a rejected command should not change anything", command)
+		}
+	}
+}
+
+func TestRectCommandRejectsBadRects(t *testing.T) {
+	for _, argument := range []string{"10,10,nan,10", "inf,10,10,10"} {
+		params := testParams()
+		editor := NewRectEditor(params.Path)
+		if err := applyRectCommand(editor, &params, argument); err == nil {
+			t.Errorf("rect %s: expected an error", argument)
+		}
+		if editor.Edited {
+			t.Errorf("rect %s: a rejected rect should not be placed", argument)
+		}
+	}
+}
+
+func TestQueryAdd(t *testing.T) {
+	params := testParams()
+	err := applyCommand(&params, "query add 100,100,40,20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []RectPath{FixedPath{PixelRect{100, 100, 40, 20}}}
+	if !reflect.DeepEqual(params.ExtraPaths, expected) {
+		t.Errorf("expected %+v, got %+v", expected, params.ExtraPaths)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	spatial "git.sequentialread.com/forest/modular-spatial-index"
+)
+
+// Curve maps the 2D input space onto a 1D key space, and rectangles onto ranges of keys. Its
+// methods are the ones the demo uses from spatial.SpatialIndex2D, so the Hilbert curve from the
+// library can be compared with other curves.
+type Curve interface {
+	Name() string
+	GetValidInputRange() (int, int)
+	GetOutputRange() ([]byte, []byte)
+	GetIndexedPoint(x, y int) ([]byte, error)
+	RectangleToIndexedRanges(x, y, width, height int, iopsCostParam float32) ([]spatial.ByteRange, error)
+}
+
+// curveNames lists the curves in the order "mapping next" and "compare next" step through them.
+var curveNames = []string{"hilbert", "morton", "rows"}
+
+// curveIndex returns the index of name in curveNames, or -1.
+func curveIndex(name string) int {
+	for i, curveName := range curveNames {
+		if curveName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// newCurve builds a curve by name. Every curve has the same input range, cell size and key
+// length as the library's index at the same bit width, so the same rectangle can be compared
+// across curves.
+func newCurve(name string, curveBits int) (Curve, error) {
+	spatialIndex, err := spatial.NewSpatialIndex2D(curveBits)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "hilbert":
+		return hilbertCurve{spatialIndex}, nil
+	case "morton":
+		return newGridCurve(name, spatialIndex, curveBits, mortonKey, mortonRanges), nil
+	case "rows":
+		return newGridCurve(name, spatialIndex, curveBits, rowMajorKey, rowMajorRanges), nil
+	}
+	return nil, fmt.Errorf("unknown curve '%s', expected one of %s", name, strings.Join(curveNames, ", "))
+}
+
+// hilbertCurve is the library's index.
+type hilbertCurve struct {
+	*spatial.SpatialIndex2D
+}
+
+func (hilbertCurve) Name() string {
+	return "hilbert"
+}
+
+// the most ranges gridCurve decomposes a rectangle into before merging them by cost. It keeps huge
+// rectangles on 64 bit curves from turning into millions of ranges.
+const maxGridCurveRanges = 1024
+
+// cellRect is a rectangle of grid cells, inclusive on all sides.
+type cellRect struct {
+	minX, minY, maxX, maxY uint64
+}
+
+// gridCurve is a curve over the same grid of cells as the library's index, that orders the cells
+// with its own key function and decomposes rectangles with its own ranges function.
+type gridCurve struct {
+	name        string
+	inputMin    int
+	inputMax    int
+	axisBits    uint
+	minKey      []byte
+	maxKey      []byte
+	key         func(x, y uint64, axisBits uint) uint64
+	exactRanges func(rect cellRect, axisBits uint) [][2]uint64
+}
+
+func newGridCurve(
+	name string,
+	spatialIndex *spatial.SpatialIndex2D,
+	curveBits int,
+	key func(x, y uint64, axisBits uint) uint64,
+	exactRanges func(rect cellRect, axisBits uint) [][2]uint64,
+) *gridCurve {
+	inputMin, inputMax := spatialIndex.GetValidInputRange()
+	minKey, maxKey := spatialIndex.GetOutputRange()
+	return &gridCurve{
+		name:        name,
+		inputMin:    inputMin,
+		inputMax:    inputMax,
+		axisBits:    uint(curveBits / 2),
+		minKey:      minKey,
+		maxKey:      maxKey,
+		key:         key,
+		exactRanges: exactRanges,
+	}
+}
+
+func (curve *gridCurve) Name() string {
+	return curve.name
+}
+
+func (curve *gridCurve) GetValidInputRange() (int, int) {
+	return curve.inputMin, curve.inputMax
+}
+
+func (curve *gridCurve) GetOutputRange() ([]byte, []byte) {
+	return curve.minKey, curve.maxKey
+}
+
+// cell returns the grid cell an input coordinate falls into.
+func (curve *gridCurve) cell(input int) uint64 {
+	span := uint64(curve.inputMax-curve.inputMin) + 1
+	// (input - inputMin) * cellsPerAxis / span, without overflowing
+	hi, lo := bits.Mul64(uint64(input-curve.inputMin), uint64(1)<<curve.axisBits)
+	cell, _ := bits.Div64(hi, lo, span)
+	return cell
+}
+
+func (curve *gridCurve) clamp(input int) int {
+	if input < curve.inputMin {
+		return curve.inputMin
+	}
+	if input > curve.inputMax {
+		return curve.inputMax
+	}
+	return input
+}
+
+// toKey encodes a key the same length as the library's keys.
+func (curve *gridCurve) toKey(value uint64) []byte {
+	key := make([]byte, len(curve.maxKey))
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i] = byte(value)
+		value >>= 8
+	}
+	return key
+}
+
+func (curve *gridCurve) GetIndexedPoint(x, y int) ([]byte, error) {
+	if x < curve.inputMin || x > curve.inputMax || y < curve.inputMin || y > curve.inputMax {
+		return nil, fmt.Errorf("%d,%d is outside the valid input range %d to %d", x, y, curve.inputMin, curve.inputMax)
+	}
+	return curve.toKey(curve.key(curve.cell(x), curve.cell(y), curve.axisBits)), nil
+}
+
+// RectangleToIndexedRanges covers the cells of the rectangle with ranges, then merges ranges
+// that are close together. An extra range is taken to cost as much as reading iopsCostParam times
+// the shorter side of the rectangle in cells, so gaps up to that long are read instead of skipped.
+func (curve *gridCurve) RectangleToIndexedRanges(x, y, width, height int, iopsCostParam float32) ([]spatial.ByteRange, error) {
+	rect := cellRect{
+		minX: curve.cell(curve.clamp(x)),
+		minY: curve.cell(curve.clamp(y)),
+		maxX: curve.cell(curve.clamp(x + width)),
+		maxY: curve.cell(curve.clamp(y + height)),
+	}
+	shortSide := rect.maxX - rect.minX + 1
+	if rect.maxY-rect.minY+1 < shortSide {
+		shortSide = rect.maxY - rect.minY + 1
+	}
+	maxGap := uint64(float64(iopsCostParam) * float64(shortSide))
+
+	var merged [][2]uint64
+	for _, rng := range curve.exactRanges(rect, curve.axisBits) {
+		last := len(merged) - 1
+		if last >= 0 && rng[0]-merged[last][1]-1 <= maxGap {
+			merged[last][1] = rng[1]
+			continue
+		}
+		merged = append(merged, rng)
+	}
+
+	byteRanges := make([]spatial.ByteRange, len(merged))
+	for i, rng := range merged {
+		byteRanges[i] = spatial.ByteRange{Start: curve.toKey(rng[0]), End: curve.toKey(rng[1])}
+	}
+	return byteRanges, nil
+}
+
+// mortonKey interleaves the bits of x and y, x in the lower bit of each pair: the Z-order curve,
+// which is what geohashes use.
+func mortonKey(x, y uint64, axisBits uint) uint64 {
+	key := uint64(0)
+	for i := uint(0); i < axisBits; i++ {
+		key |= (x>>i&1)<<(2*i) | (y>>i&1)<<(2*i+1)
+	}
+	return key
+}
+
+// mortonRanges walks the quadtree the Z-order curve follows. Every quadrant is one contiguous
+// range of keys, so quadrants inside the rectangle become ranges. Quadrants on its edge are split
+// until they are small enough that there can't be more than maxGridCurveRanges of them, and then
+// read whole. The ranges come out in key order.
+func mortonRanges(rect cellRect, axisBits uint) [][2]uint64 {
+	perimeter := 2 * ((rect.maxX - rect.minX + 1) + (rect.maxY - rect.minY + 1))
+	minSide := uint64(1)
+	for minSide < perimeter/maxGridCurveRanges {
+		minSide *= 2
+	}
+
+	var ranges [][2]uint64
+	var visit func(key, x, y, side uint64)
+	visit = func(key, x, y, side uint64) {
+		if x > rect.maxX || y > rect.maxY || x+side-1 < rect.minX || y+side-1 < rect.minY {
+			return
+		}
+		inside := x >= rect.minX && y >= rect.minY && x+side-1 <= rect.maxX && y+side-1 <= rect.maxY
+		if inside || side <= minSide {
+			// side*side is 0 for the whole 64 bit curve, and the end wraps around to the last key
+			end := key + side*side - 1
+			last := len(ranges) - 1
+			if last >= 0 && ranges[last][1]+1 == key {
+				ranges[last][1] = end
+			} else {
+				ranges = append(ranges, [2]uint64{key, end})
+			}
+			return
+		}
+		half := side / 2
+		quadrant := half * half
+		visit(key, x, y, half)
+		visit(key+quadrant, x+half, y, half)
+		visit(key+2*quadrant, x, y+half, half)
+		visit(key+3*quadrant, x+half, y+half, half)
+	}
+	visit(0, 0, 0, uint64(1)<<axisBits)
+	return ranges
+}
+
+// rowMajorKey numbers the cells row by row, like pixels in an image.
+func rowMajorKey(x, y uint64, axisBits uint) uint64 {
+	return y<<axisBits | x
+}
+
+// rowMajorRanges is one range per row of the rectangle, or one range in all if it spans whole
+// rows. When there are more than maxGridCurveRanges rows, they are read in blocks of whole rows.
+func rowMajorRanges(rect cellRect, axisBits uint) [][2]uint64 {
+	lastColumn := uint64(1)<<axisBits - 1
+	if rect.minX == 0 && rect.maxX == lastColumn {
+		return [][2]uint64{{rowMajorKey(0, rect.minY, axisBits), rowMajorKey(lastColumn, rect.maxY, axisBits)}}
+	}
+
+	rows := rect.maxY - rect.minY + 1
+	rowsPerRange := (rows + maxGridCurveRanges - 1) / maxGridCurveRanges
+	var ranges [][2]uint64
+	for y := rect.minY; y <= rect.maxY; y += rowsPerRange {
+		lastRow := y + rowsPerRange - 1
+		if lastRow > rect.maxY {
+			lastRow = rect.maxY
+		}
+		ranges = append(ranges, [2]uint64{rowMajorKey(rect.minX, y, axisBits), rowMajorKey(rect.maxX, lastRow, axisBits)})
+		if lastRow == rect.maxY {
+			break
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomCellRect is a rectangle somewhere on a grid of 2^axisBits cells per side.
+func randomCellRect(random *rand.Rand, axisBits uint) cellRect {
+	side := 1 << axisBits
+	minX, minY := random.Intn(side), random.Intn(side)
+	return cellRect{
+		minX: uint64(minX),
+		minY: uint64(minY),
+		maxX: uint64(minX + random.Intn(side-minX)),
+		maxY: uint64(minY + random.Intn(side-minY)),
+	}
+}
+
+// checkRanges brute forces every cell of rect against the ranges: each has to be covered, and the
+// ranges have to be sorted and apart. It returns how many keys the ranges cover in all.
+func checkRanges(t *testing.T, name string, rect cellRect, axisBits uint, key func(x, y uint64, axisBits uint) uint64, ranges [][2]uint64) uint64 {
+	t.Helper()
+	covered := uint64(0)
+	for i, rng := range ranges {
+		if rng[0] > rng[1] {
+			t.Fatalf("%s %+v: range %d is inside out: %v", name, rect, i, rng)
+		}
+		if i > 0 && ranges[i-1][1] >= rng[0] {
+			t.Fatalf("%s %+v: range %d %v is not after range %d %v", name, rect, i, rng, i-1, ranges[i-1])
+		}
+		covered += rng[1] - rng[0] + 1
+	}
+
+	for y := rect.minY; y <= rect.maxY; y++ {
+		for x := rect.minX; x <= rect.maxX; x++ {
+			cellKey := key(x, y, axisBits)
+			i := sort.Search(len(ranges), func(i int) bool { return ranges[i][1] >= cellKey })
+			if i == len(ranges) || ranges[i][0] > cellKey {
+				t.Fatalf("%s %+v: cell %d,%d (key %d) is not covered", name, rect, x, y, cellKey)
+			}
+		}
+	}
+	return covered
+}
+
+func TestGridCurveRangesAreExactOnSmallGrids(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for axisBits := uint(1); axisBits <= 5; axisBits++ {
+		for i := 0; i < 200; i++ {
+			rect := randomCellRect(random, axisBits)
+			cells := (rect.maxX - rect.minX + 1) * (rect.maxY - rect.minY + 1)
+
+			// small rectangles never reach maxGridCurveRanges, so both decompositions are exact
+			covered := checkRanges(t, "morton", rect, axisBits, mortonKey, mortonRanges(rect, axisBits))
+			if covered != cells {
+				t.Errorf("morton %+v at %d bits: covers %d keys for %d cells", rect, axisBits, covered, cells)
+			}
+			covered = checkRanges(t, "rows", rect, axisBits, rowMajorKey, rowMajorRanges(rect, axisBits))
+			if covered != cells {
+				t.Errorf("rows %+v at %d bits: covers %d keys for %d cells", rect, axisBits, covered, cells)
+			}
+		}
+	}
+}
+
+func TestGridCurveRangesCoarsenLargeRectangles(t *testing.T) {
+	// a perimeter of about 4000 cells, so Morton reads edge quadrants of 4x4 cells whole
+	rect := cellRect{minX: 3, minY: 5, maxX: 1020, maxY: 1000}
+	ranges := mortonRanges(rect, 10)
+	checkRanges(t, "morton", rect, 10, mortonKey, ranges)
+	if len(ranges) > maxGridCurveRanges {
+		t.Errorf("morton: %d ranges, expected at most %d", len(ranges), maxGridCurveRanges)
+	}
+
+	// more rows than ranges, so rows are read in blocks
+	rect = cellRect{minX: 7, minY: 10, maxX: 9, maxY: 3000}
+	ranges = rowMajorRanges(rect, 12)
+	checkRanges(t, "rows", rect, 12, rowMajorKey, ranges)
+	if len(ranges) > maxGridCurveRanges {
+		t.Errorf("rows: %d ranges, expected at most %d", len(ranges), maxGridCurveRanges)
+	}
+}
+
+func TestGridCurveRangesCoverWholeWideCurves(t *testing.T) {
+	// on a 64 bit curve the whole grid is every key, which Morton's end key wraps around to
+	whole := cellRect{maxX: math.MaxUint32, maxY: math.MaxUint32}
+	expected := [2]uint64{0, math.MaxUint64}
+	for name, ranges := range map[string][][2]uint64{"morton": mortonRanges(whole, 32), "rows": rowMajorRanges(whole, 32)} {
+		if len(ranges) != 1 || ranges[0] != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, ranges)
+		}
+	}
+}
+
+func TestGridCurveRectangleToIndexedRanges(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for _, name := range []string{"morton", "rows"} {
+		curve, err := newCurve(name, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputMin, inputMax := curve.GetValidInputRange()
+		span := inputMax - inputMin + 1
+		for i := 0; i < 50; i++ {
+			x, y := inputMin+random.Intn(span), inputMin+random.Intn(span)
+			width, height := random.Intn(inputMax-x+1), random.Intn(inputMax-y+1)
+			for _, cost := range []float32{0, 1, 4} {
+				byteRanges, err := curve.RectangleToIndexedRanges(x, y, width, height, cost)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ranges := make([][2]uint64, len(byteRanges))
+				for j, byteRange := range byteRanges {
+					ranges[j] = [2]uint64{keyToUint64(byteRange.Start), keyToUint64(byteRange.End)}
+				}
+
+				// the rectangle is inclusive on all sides, the same as in render
+				rect := cellRect{uint64(x - inputMin), uint64(y - inputMin), uint64(x + width - inputMin), uint64(y + height - inputMin)}
+				pointKey := func(x, y uint64, axisBits uint) uint64 {
+					key, err := curve.GetIndexedPoint(int(x)+inputMin, int(y)+inputMin)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return keyToUint64(key)
+				}
+				checkRanges(t, name, rect, 8, pointKey, ranges)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"sort"
+)
+
+// curveBufferKey is everything the mapping from a pixel of the main view to a curve point
+// depends on. When none of it changes, neither does the buffer.
+type curveBufferKey struct {
+	curve     string
+	curveBits int
+	size      int
+	height    int
+	view      Viewport
+}
+
+// curveBuffer holds the curve point under every pixel of the main view, row by row, so that
+// frames only have to call GetIndexedPoint again when the curve, the view or the bit width
+// changes.
+type curveBuffer struct {
+	key         curveBufferKey
+	points      []uint64
+	visibleMin  uint64
+	visibleMax  uint64
+	initialized bool
+}
+
+// curvePoints returns the buffer for the given view, recomputing it only when the key changed.
+func (generator *FrameGenerator) curvePoints(curve Curve, key curveBufferKey) (*curveBuffer, error) {
+	buffer := &generator.curveBuffer
+	if buffer.initialized && buffer.key == key {
+		return buffer, nil
+	}
+
+	inputMin, inputMax := curve.GetValidInputRange()
+	if len(buffer.points) != key.size*key.height {
+		buffer.points = make([]uint64, key.size*key.height)
+	}
+	workers := generator.workers()
+	bandMins := make([]uint64, workers)
+	bandMaxes := make([]uint64, workers)
+	bandErrors := make([]error, workers)
+	for band := range bandMins {
+		bandMins[band] = ^uint64(0)
+	}
+	forEachBand(key.height, workers, func(band, minY, maxY int) {
+		for y := minY; y < maxY; y++ {
+			for x := 0; x < key.size; x++ {
+				worldX, worldY := key.view.ScreenToWorld(float64(x), float64(y), key.size)
+				remappedX := int(lerp(float64(inputMin), float64(inputMax), worldX))
+				remappedY := int(lerp(float64(inputMin), float64(inputMax), worldY))
+
+				curvePointBytes, err := curve.GetIndexedPoint(remappedX, remappedY)
+				if err != nil {
+					bandErrors[band] = err
+					return
+				}
+				curvePoint := keyToUint64(curvePointBytes)
+				buffer.points[y*key.size+x] = curvePoint
+				if curvePoint < bandMins[band] {
+					bandMins[band] = curvePoint
+				}
+				if curvePoint > bandMaxes[band] {
+					bandMaxes[band] = curvePoint
+				}
+			}
+		}
+	})
+
+	buffer.visibleMin = ^uint64(0)
+	buffer.visibleMax = 0
+	for band := range bandErrors {
+		if bandErrors[band] != nil {
+			buffer.initialized = false
+			return nil, bandErrors[band]
+		}
+		if bandMins[band] < buffer.visibleMin {
+			buffer.visibleMin = bandMins[band]
+		}
+		if bandMaxes[band] > buffer.visibleMax {
+			buffer.visibleMax = bandMaxes[band]
+		}
+	}
+	buffer.key = key
+	buffer.initialized = true
+	return buffer, nil
+}
+
+// rangeSet answers "which range contains this curve point" with a binary search instead of a
+// scan over every range. Overlapping or touching ranges are merged, and the merged interval
+// answers with the index of the first range in it.
+type rangeSet struct {
+	starts  []uint64
+	ends    []uint64
+	indexes []int
+}
+
+func newRangeSet(ranges [][]uint64) rangeSet {
+	order := make([]int, len(ranges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return ranges[order[a]][0] < ranges[order[b]][0]
+	})
+
+	set := rangeSet{}
+	for _, index := range order {
+		start, end := ranges[index][0], ranges[index][1]
+		last := len(set.ends) - 1
+		if last >= 0 && (start <= set.ends[last] || start-set.ends[last] == 1) {
+			if end > set.ends[last] {
+				set.ends[last] = end
+			}
+			continue
+		}
+		set.starts = append(set.starts, start)
+		set.ends = append(set.ends, end)
+		set.indexes = append(set.indexes, index)
+	}
+	return set
+}
+
+// indexOf returns the index of the range containing point, or -1.
+func (set rangeSet) indexOf(point uint64) int {
+	i := sort.Search(len(set.starts), func(i int) bool {
+		return set.starts[i] > point
+	}) - 1
+	if i >= 0 && point <= set.ends[i] {
+		return set.indexes[i]
+	}
+	return -1
+}
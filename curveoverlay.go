@@ -0,0 +1,117 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// the curve overlay walks every cell of the curve, so it is only offered for curves small enough
+// to walk: 16 bits is 8 bits per axis, 256 x 256 cells.
+const maxCurveOverlayBits = 16
+
+var curveOverlayColor = color.RGBA{0x90, 0x90, 0x90, 0xff}
+
+// curveCell is the center of a cell in index space. Offsets that no cell mapped to are not found.
+type curveCell struct {
+	x, y  float64
+	found bool
+}
+
+// curveCells returns the center of the cell at each curve offset. The library only maps points
+// to the curve, not back, so this asks it for the curve point of every cell and inverts that.
+// The result is cached per curve and bit width.
+func (generator *FrameGenerator) curveCells(curve Curve, curveBits int) ([]curveCell, error) {
+	if cells, has := generator.curveOverlays[curveID{curve.Name(), curveBits}]; has {
+		return cells, nil
+	}
+
+	inputMin, inputMax := curve.GetValidInputRange()
+	cellsPerAxis := 1 << uint(curveBits/2)
+	cellWidth := (float64(inputMax) - float64(inputMin) + 1) / float64(cellsPerAxis)
+
+	cells := make([]curveCell, cellsPerAxis*cellsPerAxis)
+	for cellY := 0; cellY < cellsPerAxis; cellY++ {
+		for cellX := 0; cellX < cellsPerAxis; cellX++ {
+			center := curveCell{
+				x:     float64(inputMin) + (float64(cellX)+0.5)*cellWidth,
+				y:     float64(inputMin) + (float64(cellY)+0.5)*cellWidth,
+				found: true,
+			}
+			curvePointBytes, err := curve.GetIndexedPoint(int(math.Floor(center.x)), int(math.Floor(center.y)))
+			if err != nil {
+				return nil, err
+			}
+			curvePoint := keyToUint64(curvePointBytes)
+			if curvePoint < uint64(len(cells)) {
+				cells[curvePoint] = center
+			}
+		}
+	}
+
+	generator.curveOverlays[curveID{curve.Name(), curveBits}] = cells
+	return cells, nil
+}
+
+// drawCurveOverlay draws the curve as a line through the centers of its cells, in order, clipped
+// to area. Segments between two points the ranges read are drawn white, the rest gray.
+func drawCurveOverlay(rgba *image.RGBA, area image.Rectangle, cells []curveCell, toScreen func(curveCell) (float64, float64), ranges rangeSet) {
+	for i := 0; i+1 < len(cells); i++ {
+		if !cells[i].found || !cells[i+1].found {
+			continue
+		}
+		segmentColor := curveOverlayColor
+		if ranges.indexOf(uint64(i)) != -1 && ranges.indexOf(uint64(i+1)) != -1 {
+			segmentColor = white
+		}
+		x0, y0 := toScreen(cells[i])
+		x1, y1 := toScreen(cells[i+1])
+		drawLine(rgba, area, x0, y0, x1, y1, segmentColor)
+	}
+}
+
+// drawLine draws a one pixel wide line from x0,y0 to x1,y1, skipping everything outside area.
+// It clips the line first, so lines that are mostly off screen at high zoom stay cheap.
+func drawLine(rgba *image.RGBA, area image.Rectangle, x0, y0, x1, y1 float64, lineColor color.RGBA) {
+	// Liang-Barsky: the part of the line between t0 and t1 is inside the area
+	dx, dy := x1-x0, y1-y0
+	t0, t1 := 0.0, 1.0
+	edges := [][2]float64{
+		{-dx, x0 - float64(area.Min.X)},
+		{dx, float64(area.Max.X) - 1 - x0},
+		{-dy, y0 - float64(area.Min.Y)},
+		{dy, float64(area.Max.Y) - 1 - y0},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 && t > t0 {
+			t0 = t
+		} else if p > 0 && t < t1 {
+			t1 = t
+		}
+	}
+	if t0 > t1 {
+		return
+	}
+
+	startX, startY := x0+t0*dx, y0+t0*dy
+	endX, endY := x0+t1*dx, y0+t1*dy
+	steps := int(math.Ceil(math.Max(math.Abs(endX-startX), math.Abs(endY-startY))))
+	for step := 0; step <= steps; step++ {
+		amount := 0.0
+		if steps > 0 {
+			amount = float64(step) / float64(steps)
+		}
+		point := image.Point{int(math.Round(lerp(startX, endX, amount))), int(math.Round(lerp(startY, endY, amount)))}
+		if point.In(area) {
+			setPixel(rgba, point.X, point.Y, lineColor)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"time"
+)
+
+// Demo is the state of an interactive session: what is being rendered, and how input and
+// commands change it. Each frame it renders is logged to Stats every tenth frame.
+type Demo struct {
+	Generator *FrameGenerator
+	Params    FrameParams
+	Editor    *RectEditor
+	Clock     Clock
+	Frame     int
+	Stats     io.Writer
+
+	// History has the samples for the plot, and every frame's when it is set to keep them all for -csv.
+	History History
+
+	// Commands are applied before the next frame, so they can come from another goroutine.
+	Commands chan string
+
+	lastFrame time.Time
+}
+
+// how much each frame moves the FPS shown in the HUD, so that it doesn't flicker
+const fpsSmoothing = 0.1
+
+// NewDemo wraps the rectangle path in params with a RectEditor so the user can take over.
+func NewDemo(generator *FrameGenerator, params FrameParams, clock Clock, stats io.Writer) *Demo {
+	editor := NewRectEditor(params.Path)
+	params.Path = editor
+	return &Demo{
+		Generator: generator,
+		Params:    params,
+		Editor:    editor,
+		Clock:     clock,
+		Stats:     stats,
+		Commands:  make(chan string, 16),
+	}
+}
+
+func (demo *Demo) HandleInput(event InputEvent) {
+	switch event.Kind {
+	case InputPointer:
+		handleScreenPointer(demo.Generator, &demo.Params, demo.Editor, event.Pointer)
+	case InputChar:
+		if command, has := keyBindings[event.Char]; has {
+			demo.RunCommand(command)
+		}
+	case InputCommand:
+		demo.RunCommand(event.Command)
+	}
+}
+
+func (demo *Demo) RunCommand(command string) {
+	err := applyCommand(&demo.Params, command)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf(
+		"%s -> bits: %d, iopsCostParam: %g, view: %s\n",
+		command, demo.Params.CurveBits, demo.Params.IOPSCostParam, formatView(demo.Params.View),
+	)
+}
+
+// RenderFrame applies pending commands and renders the next frame.
+func (demo *Demo) RenderFrame() (*image.RGBA, error) {
+	for len(demo.Commands) > 0 {
+		demo.RunCommand(<-demo.Commands)
+	}
+
+	now := time.Now()
+	if !demo.lastFrame.IsZero() {
+		fps := 1 / now.Sub(demo.lastFrame).Seconds()
+		if demo.Params.FPS == 0 {
+			demo.Params.FPS = fps
+		} else {
+			demo.Params.FPS = lerp(demo.Params.FPS, fps, fpsSmoothing)
+		}
+	}
+	demo.lastFrame = now
+
+	seconds := demo.Clock.Seconds(demo.Frame)
+	rgba, stats, err := demo.Generator.Render(seconds, demo.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	demo.History.Add(demo.Frame, seconds, demo.Params, stats)
+	if demo.Params.Plot {
+		drawPlot(rgba, demo.History.Last(demo.Params.PlotSeconds), demo.Params.PlotSeconds)
+	}
+
+	if demo.Frame%10 == 0 {
+		fmt.Fprintln(demo.Stats, statsLine(demo.Frame, seconds, demo.Params, stats))
+	}
+	demo.Frame++
+
+	return rgba, nil
+}
+
+// run_display is the main loop: deliver input, render, present, until the display wants to close.
+func run_display(display Display, demo *Demo) (err error) {
+	defer func() {
+		closeErr := display.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	for !display.ShouldClose() {
+		for _, event := range display.PollInput() {
+			demo.HandleInput(event)
+		}
+
+		rgba, err := demo.RenderFrame()
+		if err != nil {
+			return err
+		}
+		err = display.Present(rgba)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestRunDisplayEndToEnd(t *testing.T) {
+	params := testParams()
+	demo := NewDemo(NewFrameGenerator(), params, SteppedClock{FPS: 30}, io.Discard)
+	display := &FakeDisplay{
+		MaxFrames: 8,
+		Input: map[int][]InputEvent{
+			1: {{Kind: InputChar, Char: '['}},
+			2: {{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerDown, Button: PrimaryButton, X: 16, Y: 32}}},
+			3: {{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerMove, X: 48, Y: 40}}},
+			4: {{Kind: InputPointer, Pointer: PointerEvent{Kind: PointerUp, Button: PrimaryButton, X: 64, Y: 48}}},
+		},
+	}
+
+	err := run_display(display, demo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(display.Frames) != display.MaxFrames || demo.Frame != display.MaxFrames {
+		t.Errorf("expected %d frames, presented %d and rendered %d", display.MaxFrames, len(display.Frames), demo.Frame)
+	}
+	for i, frame := range display.Frames {
+		if frame.Rect.Dx() != params.Size || frame.Rect.Dy() != params.Size {
+			t.Errorf("frame %d is %v, expected %dx%d", i, frame.Rect, params.Size, params.Size)
+		}
+	}
+	if demo.Params.IOPSCostParam != 0.5 {
+		t.Errorf("'[' should have stepped iopsCostParam down to 0.5, got %g", demo.Params.IOPSCostParam)
+	}
+	expected := QueryRect{0.125, 0.25, 0.5, 0.375}
+	if !demo.Editor.Edited || demo.Editor.Rect != expected {
+		t.Errorf("the drag should have drawn %+v, got %+v (edited: %t)", expected, demo.Editor.Rect, demo.Editor.Edited)
+	}
+	if !display.Closed {
+		t.Error("run_display returned without closing the display")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"image"
+	"os"
+	"os/signal"
+)
+
+type InputEventKind int
+
+const (
+	InputPointer InputEventKind = iota
+	InputChar
+	InputCommand
+)
+
+// InputEvent is something the user did: a PointerEvent in frame pixels, a typed character, or a
+// text command for applyCommand.
+type InputEvent struct {
+	Kind    InputEventKind
+	Pointer PointerEvent
+	Char    rune
+	Command string
+}
+
+// Display is somewhere frames are shown and input comes from. run_display drives any Display,
+// so the main loop doesn't know whether it is talking to an OpenGL window or something else.
+type Display interface {
+	// Present shows a frame.
+	Present(rgba *image.RGBA) error
+
+	// PollInput returns the input that arrived since the last call.
+	PollInput() []InputEvent
+
+	// ShouldClose reports whether the user asked to quit.
+	ShouldClose() bool
+
+	// Close releases whatever the display holds. run_display always calls it before returning.
+	Close() error
+}
+
+// multiDisplay shows the same frames on several displays at once, such as the OpenGL window and
+// the browser viewer, and takes input from all of them.
+type multiDisplay []Display
+
+func (displays multiDisplay) Present(rgba *image.RGBA) error {
+	for _, display := range displays {
+		err := display.Present(rgba)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (displays multiDisplay) PollInput() []InputEvent {
+	var input []InputEvent
+	for _, display := range displays {
+		input = append(input, display.PollInput()...)
+	}
+	return input
+}
+
+// ShouldClose reports whether any of the displays wants to close: closing the window ends the demo.
+func (displays multiDisplay) ShouldClose() bool {
+	for _, display := range displays {
+		if display.ShouldClose() {
+			return true
+		}
+	}
+	return false
+}
+
+func (displays multiDisplay) Close() error {
+	var firstErr error
+	for _, display := range displays {
+		err := display.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// signalDisplay shows nothing, it only asks to close once the process is sent one of the signals
+// it was made for. With it among the displays, Ctrl-C or a kill ends the frame loop the same way
+// closing the window does, so everything after run_display still runs.
+type signalDisplay struct {
+	signals  chan os.Signal
+	received bool
+}
+
+func newSignalDisplay(signals ...os.Signal) *signalDisplay {
+	display := &signalDisplay{signals: make(chan os.Signal, 1)}
+	signal.Notify(display.signals, signals...)
+	return display
+}
+
+func (display *signalDisplay) Present(rgba *image.RGBA) error {
+	return nil
+}
+
+func (display *signalDisplay) PollInput() []InputEvent {
+	return nil
+}
+
+func (display *signalDisplay) ShouldClose() bool {
+	select {
+	case <-display.signals:
+		display.received = true
+	default:
+	}
+	return display.received
+}
+
+// Close hands the signals back, so a second Ctrl-C kills the process as usual.
+func (display *signalDisplay) Close() error {
+	signal.Stop(display.signals)
+	return nil
+}
+
+// DisplayResources counts what a display holds on to. The live counts should stay the same from
+// one frame to the next; if they grow, the display leaks. TextureAllocations counts every time
+// texture storage was (re)allocated, which should only happen when the frame size changes.
+// Closing a display releases everything, so all of them are 0 afterwards.
+type DisplayResources struct {
+	Textures           int
+	VertexArrays       int
+	Buffers            int
+	TextureAllocations int
+}
+
+// ResourceReporter is implemented by displays that can report their DisplayResources.
+type ResourceReporter interface {
+	Resources() DisplayResources
+}
+
+// FakeDisplay is an in-memory Display for driving the demo without a window or a GPU.
+// It delivers Input[n] before frame n is rendered, keeps every presented frame, and asks to
+// close after MaxFrames frames. Like the OpenGL display, it sets up one vertex array and buffer
+// for the quad on the first frame, copies each frame into one "texture" that is only reallocated
+// when the frame size changes, and reports all of them in Resources.
+type FakeDisplay struct {
+	MaxFrames int
+	Input     map[int][]InputEvent
+	Frames    []*image.RGBA
+	Closed    bool
+
+	texture   *image.RGBA
+	resources DisplayResources
+}
+
+func (display *FakeDisplay) Present(rgba *image.RGBA) error {
+	if display.resources.VertexArrays == 0 {
+		display.resources.VertexArrays++
+		display.resources.Buffers++
+	}
+	if display.texture == nil || display.texture.Rect.Size() != rgba.Rect.Size() {
+		if display.texture == nil {
+			display.resources.Textures++
+		}
+		display.texture = image.NewRGBA(image.Rectangle{Max: rgba.Rect.Size()})
+		display.resources.TextureAllocations++
+	}
+	copy(display.texture.Pix, rgba.Pix)
+
+	display.Frames = append(display.Frames, rgba)
+	return nil
+}
+
+func (display *FakeDisplay) Resources() DisplayResources {
+	return display.resources
+}
+
+func (display *FakeDisplay) PollInput() []InputEvent {
+	return display.Input[len(display.Frames)]
+}
+
+func (display *FakeDisplay) ShouldClose() bool {
+	return len(display.Frames) >= display.MaxFrames
+}
+
+func (display *FakeDisplay) Close() error {
+	if display.texture != nil {
+		display.texture = nil
+		display.resources.Textures--
+	}
+	display.resources = DisplayResources{}
+	display.Closed = true
+	return nil
+}
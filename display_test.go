@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestFakeDisplayResourcesStayFlat(t *testing.T) {
+	display := &FakeDisplay{}
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	err := display.Present(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := display.Resources()
+	expected := DisplayResources{Textures: 1, VertexArrays: 1, Buffers: 1, TextureAllocations: 1}
+	if first != expected {
+		t.Fatalf("after the first frame, expected %+v, got %+v", expected, first)
+	}
+
+	for i := 0; i < 500; i++ {
+		err := display.Present(image.NewRGBA(image.Rect(0, 0, 64, 64)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resources := display.Resources(); resources != first {
+			t.Fatalf("frame %d of the same size changed the resources from %+v to %+v", i+1, first, resources)
+		}
+	}
+
+	// only a new size reallocates the texture
+	err = display.Present(image.NewRGBA(image.Rect(0, 0, 32, 48)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.TextureAllocations = 2
+	if resources := display.Resources(); resources != expected {
+		t.Fatalf("after resizing, expected %+v, got %+v", expected, resources)
+	}
+
+	err = display.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources := display.Resources(); resources != (DisplayResources{}) {
+		t.Errorf("Close should release everything, still holding %+v", resources)
+	}
+}
+
+func TestSignalDisplayEndsTheLoop(t *testing.T) {
+	signals := &signalDisplay{signals: make(chan os.Signal, 1)}
+	if signals.ShouldClose() {
+		t.Fatal("should not close before a signal")
+	}
+	signals.signals <- os.Interrupt
+	if !signals.ShouldClose() || !signals.ShouldClose() {
+		t.Fatal("should keep asking to close after a signal")
+	}
+
+	fake := &FakeDisplay{}
+	demo := NewDemo(NewFrameGenerator(), testParams(), SteppedClock{FPS: 30}, io.Discard)
+	err := run_display(multiDisplay{fake, signals}, demo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Frames) != 0 || !fake.Closed {
+		t.Errorf("expected no frames and a closed display, got %d frames (closed: %t)", len(fake.Frames), fake.Closed)
+	}
+}
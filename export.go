@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	imagedraw "image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// FrameWriter receives rendered frames one at a time and encodes them somewhere.
+type FrameWriter interface {
+	WriteFrame(rgba *image.RGBA) error
+	Close() error
+}
+
+// newFrameWriter makes a writer for format. The palette the frames are colored with is needed
+// for GIF, which can only use 256 colors.
+func newFrameWriter(format, output string, fps float64, palette Palette) (FrameWriter, error) {
+	switch format {
+	case "png":
+		return newPNGSequenceWriter(output)
+	case "gif":
+		return newGIFWriter(output, fps, palette)
+	case "y4m":
+		return newY4MWriter(output, fps)
+	}
+	return nil, fmt.Errorf("unknown export format '%s', expected png, gif or y4m", format)
+}
+
+// createOutput opens the output file, treating "-" as stdout so streams can be piped into ffmpeg & friends.
+func createOutput(output string) (io.WriteCloser, error) {
+	if output == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(output)
+}
+
+// pngSequenceWriter writes frame_00000.png, frame_00001.png, ... into a directory.
+type pngSequenceWriter struct {
+	directory string
+	index     int
+}
+
+func newPNGSequenceWriter(directory string) (*pngSequenceWriter, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &pngSequenceWriter{directory: directory}, nil
+}
+
+func (writer *pngSequenceWriter) WriteFrame(rgba *image.RGBA) error {
+	filename := filepath.Join(writer.directory, fmt.Sprintf("frame_%05d.png", writer.index))
+	writer.index++
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, rgba)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (writer *pngSequenceWriter) Close() error {
+	return nil
+}
+
+// gifWriter collects paletted frames in memory and encodes the animated GIF on Close,
+// because the GIF format needs every frame before it can be written.
+type gifWriter struct {
+	output  string
+	delay   int
+	palette color.Palette
+	gif     gif.GIF
+}
+
+func newGIFWriter(output string, fps float64, palette Palette) (*gifWriter, error) {
+	// GIF frame delays are in hundredths of a second
+	delay := int(math.Round(float64(100) / fps))
+	if delay < 1 {
+		delay = 1
+	}
+	return &gifWriter{
+		output:  output,
+		delay:   delay,
+		palette: gifPalette(palette),
+	}, nil
+}
+
+// gifPalette is built from the same palette the frame generator colors the curve with, dim and
+// bright, plus the black and white used for the outline and the number line, and the colors of
+// missed pixels, highlights, queries and ranges. That way the GIF needs little dithering.
+func gifPalette(palette Palette) color.Palette {
+	colorCount := 116
+	colors := color.Palette{color.Black, color.White, missedColor, highlightColor, numberLineAxisColor, overlapColor}
+	for _, queryColor := range queryColors[1:] {
+		colors = append(colors, queryColor)
+	}
+	for _, rangeColorValue := range rangeColors {
+		colors = append(colors, rangeColorValue)
+	}
+	for _, dimmed := range []bool{true, false} {
+		for i := 0; i < colorCount; i++ {
+			colors = append(colors, palette.Color(float64(i)/float64(colorCount), dimmed))
+		}
+	}
+	return colors
+}
+
+func (writer *gifWriter) WriteFrame(rgba *image.RGBA) error {
+	paletted := image.NewPaletted(rgba.Bounds(), writer.palette)
+	imagedraw.Draw(paletted, paletted.Rect, rgba, rgba.Bounds().Min, imagedraw.Src)
+
+	writer.gif.Image = append(writer.gif.Image, paletted)
+	writer.gif.Delay = append(writer.gif.Delay, writer.delay)
+	return nil
+}
+
+func (writer *gifWriter) Close() error {
+	file, err := createOutput(writer.output)
+	if err != nil {
+		return err
+	}
+	err = gif.EncodeAll(file, &writer.gif)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// y4mWriter writes a raw YUV4MPEG2 stream with 4:2:0 chroma subsampling, which ffmpeg, mpv and
+// most encoders accept directly.
+type y4mWriter struct {
+	file          io.WriteCloser
+	writer        *bufio.Writer
+	fps           float64
+	headerWritten bool
+}
+
+func newY4MWriter(output string, fps float64) (*y4mWriter, error) {
+	file, err := createOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	return &y4mWriter{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		fps:    fps,
+	}, nil
+}
+
+func (writer *y4mWriter) WriteFrame(rgba *image.RGBA) error {
+	bounds := rgba.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if !writer.headerWritten {
+		_, err := fmt.Fprintf(
+			writer.writer, "YUV4MPEG2 W%d H%d F%d:1000 Ip A1:1 C420jpeg\n",
+			width, height, int(math.Round(writer.fps*float64(1000))),
+		)
+		if err != nil {
+			return err
+		}
+		writer.headerWritten = true
+	}
+
+	chromaWidth := (width + 1) / 2
+	chromaHeight := (height + 1) / 2
+	yPlane := make([]byte, width*height)
+	cbSums := make([]int, chromaWidth*chromaHeight)
+	crSums := make([]int, chromaWidth*chromaHeight)
+	sampleCounts := make([]int, chromaWidth*chromaHeight)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := rgba.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			luma, cb, cr := color.RGBToYCbCr(rgba.Pix[offset], rgba.Pix[offset+1], rgba.Pix[offset+2])
+			yPlane[y*width+x] = luma
+
+			chromaIndex := (y/2)*chromaWidth + x/2
+			cbSums[chromaIndex] += int(cb)
+			crSums[chromaIndex] += int(cr)
+			sampleCounts[chromaIndex]++
+		}
+	}
+	cbPlane := make([]byte, len(cbSums))
+	crPlane := make([]byte, len(crSums))
+	for i := range sampleCounts {
+		cbPlane[i] = byte(cbSums[i] / sampleCounts[i])
+		crPlane[i] = byte(crSums[i] / sampleCounts[i])
+	}
+
+	for _, chunk := range [][]byte{[]byte("FRAME\n"), yPlane, cbPlane, crPlane} {
+		_, err := writer.writer.Write(chunk)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *y4mWriter) Close() error {
+	err := writer.writer.Flush()
+	if err != nil {
+		writer.file.Close()
+		return err
+	}
+	return writer.file.Close()
+}
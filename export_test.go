@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestY4MWriterOddSize(t *testing.T) {
+	// 5x3 frames have a last chroma column and row that only cover one pixel each way
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	frame := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			frame.Set(x, y, red)
+		}
+		frame.Set(4, y, blue)
+	}
+
+	output := filepath.Join(t.TempDir(), "frames.y4m")
+	writer, err := newY4MWriter(output, 29.97)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		err = writer.WriteFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := "YUV4MPEG2 W5 H3 F29970:1000 Ip A1:1 C420jpeg\n"
+	if !bytes.HasPrefix(stream, []byte(header)) {
+		t.Fatalf("expected the header %q, got %q", header, stream[:bytes.IndexByte(stream, '\n')+1])
+	}
+	// FRAME, then 5x3 luma samples, then 3x2 samples of each of the two chroma planes
+	frameLength := len("FRAME\n") + 5*3 + 3*2 + 3*2
+	if len(stream) != len(header)+2*frameLength {
+		t.Fatalf("expected %d bytes, got %d", len(header)+2*frameLength, len(stream))
+	}
+
+	redY, redCb, redCr := color.RGBToYCbCr(red.R, red.G, red.B)
+	blueY, blueCb, blueCr := color.RGBToYCbCr(blue.R, blue.G, blue.B)
+	for i := 0; i < 2; i++ {
+		frameBytes := stream[len(header)+i*frameLength:][:frameLength]
+		if !bytes.HasPrefix(frameBytes, []byte("FRAME\n")) {
+			t.Fatalf("frame %d does not start with FRAME", i)
+		}
+		planes := frameBytes[len("FRAME\n"):]
+		luma, cb, cr := planes[:15], planes[15:21], planes[21:27]
+		for y := 0; y < 3; y++ {
+			expected := []byte{redY, redY, redY, redY, blueY}
+			if row := luma[y*5 : y*5+5]; !bytes.Equal(row, expected) {
+				t.Errorf("frame %d, luma row %d: expected %v, got %v", i, y, expected, row)
+			}
+		}
+		// the last chroma column only covers the blue column
+		for _, plane := range []struct {
+			name    string
+			samples []byte
+			red     byte
+			blue    byte
+		}{{"cb", cb, redCb, blueCb}, {"cr", cr, redCr, blueCr}} {
+			expected := []byte{plane.red, plane.red, plane.blue, plane.red, plane.red, plane.blue}
+			if !bytes.Equal(plane.samples, expected) {
+				t.Errorf("frame %d, %s: expected %v, got %v", i, plane.name, expected, plane.samples)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// a tiny 3x5 pixel font, enough to label things inside the frame without pulling in a font
+// package. Each glyph is five rows of three pixels, '#' is a lit pixel. Lowercase letters are
+// drawn as uppercase.
+const glyphWidth = 3
+const glyphHeight = 5
+const glyphAdvance = glyphWidth + 1
+
+var glyphs = map[rune][glyphHeight]string{
+	'0':  {"###", "#.#", "#.#", "#.#", "###"},
+	'1':  {".#.", "##.", ".#.", ".#.", "###"},
+	'2':  {"##.", "..#", ".#.", "#..", "###"},
+	'3':  {"##.", "..#", ".#.", "..#", "##."},
+	'4':  {"#.#", "#.#", "###", "..#", "..#"},
+	'5':  {"###", "#..", "##.", "..#", "##."},
+	'6':  {".##", "#..", "###", "#.#", "###"},
+	'7':  {"###", "..#", ".#.", ".#.", ".#."},
+	'8':  {"###", "#.#", "###", "#.#", "###"},
+	'9':  {"###", "#.#", "###", "..#", "##."},
+	'A':  {".#.", "#.#", "###", "#.#", "#.#"},
+	'B':  {"##.", "#.#", "##.", "#.#", "##."},
+	'C':  {".##", "#..", "#..", "#..", ".##"},
+	'D':  {"##.", "#.#", "#.#", "#.#", "##."},
+	'E':  {"###", "#..", "##.", "#..", "###"},
+	'F':  {"###", "#..", "##.", "#..", "#.."},
+	'G':  {".##", "#..", "#.#", "#.#", ".##"},
+	'H':  {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I':  {"###", ".#.", ".#.", ".#.", "###"},
+	'J':  {"..#", "..#", "..#", "#.#", ".#."},
+	'K':  {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L':  {"#..", "#..", "#..", "#..", "###"},
+	'M':  {"#.#", "###", "###", "#.#", "#.#"},
+	'N':  {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O':  {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P':  {"##.", "#.#", "##.", "#..", "#.."},
+	'Q':  {".#.", "#.#", "#.#", "##.", ".##"},
+	'R':  {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S':  {".##", "#..", ".#.", "..#", "##."},
+	'T':  {"###", ".#.", ".#.", ".#.", ".#."},
+	'U':  {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V':  {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W':  {"#.#", "#.#", "###", "###", "#.#"},
+	'X':  {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y':  {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z':  {"###", "..#", ".#.", "#..", "###"},
+	' ':  {"...", "...", "...", "...", "..."},
+	'.':  {"...", "...", "...", "...", ".#."},
+	',':  {"...", "...", "...", ".#.", "#.."},
+	':':  {"...", ".#.", "...", ".#.", "..."},
+	'-':  {"...", "...", "###", "...", "..."},
+	'+':  {"...", ".#.", "###", ".#.", "..."},
+	'/':  {"..#", "..#", ".#.", "#..", "#.."},
+	'%':  {"#.#", "..#", ".#.", "#..", "#.#"},
+	'#':  {"#.#", "###", "#.#", "###", "#.#"},
+	'=':  {"...", "###", "...", "###", "..."},
+	'(':  {".#.", "#..", "#..", "#..", ".#."},
+	')':  {".#.", "..#", "..#", "..#", ".#."},
+	'[':  {"##.", "#..", "#..", "#..", "##."},
+	']':  {".##", "..#", "..#", "..#", ".##"},
+	'_':  {"...", "...", "...", "...", "###"},
+	'<':  {"..#", ".#.", "#..", ".#.", "..#"},
+	'>':  {"#..", ".#.", "..#", ".#.", "#.."},
+	'?':  {"##.", "..#", ".#.", "...", ".#."},
+	'!':  {".#.", ".#.", ".#.", "...", ".#."},
+	'\'': {".#.", ".#.", "...", "...", "..."},
+	'*':  {"...", "#.#", ".#.", "#.#", "..."},
+}
+
+// textWidth is how many pixels wide drawText will draw text at the given scale.
+func textWidth(text string, scale int) int {
+	length := len([]rune(text))
+	if length == 0 {
+		return 0
+	}
+	return (length*glyphAdvance - 1) * scale
+}
+
+// drawText draws text with its top left corner at x,y. Characters without a glyph are drawn as
+// '?', and pixels outside the image are skipped.
+func drawText(rgba *image.RGBA, x, y int, text string, textColor color.RGBA, scale int) {
+	bounds := rgba.Bounds()
+	for i, char := range []rune(strings.ToUpper(text)) {
+		glyph, has := glyphs[char]
+		if !has {
+			glyph = glyphs['?']
+		}
+		glyphX := x + i*glyphAdvance*scale
+		for row := 0; row < glyphHeight; row++ {
+			for column := 0; column < glyphWidth; column++ {
+				if glyph[row][column] != '#' {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						point := image.Point{glyphX + column*scale + dx, y + row*scale + dy}
+						if point.In(bounds) {
+							rgba.SetRGBA(point.X, point.Y, textColor)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// how far the HUD is from the corner of the frame, and how far its text is from the edge of its box
+const hudMargin = 4
+const hudPadding = 3
+const hudLineHeight = glyphHeight + 3
+
+// drawHUD draws lines of text in the top left corner.
+func drawHUD(rgba *image.RGBA, lines []string) {
+	drawTextBox(rgba, hudMargin, hudMargin, lines)
+}
+
+// textBoxSize is how big drawTextBox draws lines, including the box.
+func textBoxSize(lines []string) (int, int) {
+	width := 0
+	for _, line := range lines {
+		if lineWidth := textWidth(line, 1); lineWidth > width {
+			width = lineWidth
+		}
+	}
+	return width + hudPadding*2, len(lines)*hudLineHeight - (hudLineHeight - glyphHeight) + hudPadding*2
+}
+
+// drawTextBox draws lines of text with the top left corner of their box at x,y. The box darkens
+// what is under it so the text stays readable on top of anything.
+func drawTextBox(rgba *image.RGBA, x, y int, lines []string) {
+	width, height := textBoxSize(lines)
+	box := image.Rect(x, y, x+width, y+height).Intersect(rgba.Bounds())
+	for boxY := box.Min.Y; boxY < box.Max.Y; boxY++ {
+		for boxX := box.Min.X; boxX < box.Max.X; boxX++ {
+			rgba.SetRGBA(boxX, boxY, mix(rgba.RGBAAt(boxX, boxY), black, 0.7))
+		}
+	}
+	for i, line := range lines {
+		drawText(rgba, x+hudPadding, y+hudPadding+i*hudLineHeight, line, white, 1)
+	}
+}
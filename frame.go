@@ -0,0 +1,669 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// FrameParams holds everything about a frame that is not derived from the animation time.
+type FrameParams struct {
+	Size          int
+	CurveBits     int
+	IOPSCostParam float32
+	Path          RectPath
+	View          Viewport
+
+	// Curve names the curve the cells are ordered by, one of curveNames. When CompareCurve names
+	// another one, the frame is split into two panels that render the same rectangles on Curve on
+	// the left and CompareCurve on the right, each with its range count and oversampling.
+	Curve        string
+	CompareCurve string
+
+	// ExtraPaths are more query rectangles, queried alongside Path as if they were one batch.
+	ExtraPaths []RectPath
+
+	// ColorMode picks what the hue of a cell means. With CycleRanges, the ranges take turns
+	// being shown one at a time, rangeCycleSeconds each, and every cell outside it is dimmed.
+	ColorMode   ColorMode
+	CycleRanges bool
+
+	// Palette colors the cells by their position along the curve, going through it PaletteCycles times.
+	Palette       Palette
+	PaletteCycles float64
+
+	// HUD draws the stats into the top left corner of the frame. FPS is only for showing there:
+	// the demo measures it, and headless exports show the rate they are encoded at.
+	HUD bool
+	FPS float64
+
+	// Plot draws a chart of the range count, oversampling and rectangle area of the last
+	// PlotSeconds of animation time above the number line. Render doesn't keep track of past
+	// frames, so the demo and headless exports draw it from their History.
+	Plot        bool
+	PlotSeconds float64
+
+	// CurveOverlay draws the curve itself as a line through its cells, at up to maxCurveOverlayBits.
+	CurveOverlay bool
+
+	// Inspect shows what is under the pointer: hovering the main view shows the cell and marks its
+	// offset on the number line, and hovering the number line lights up the cells in that bucket.
+	Inspect bool
+
+	// Pointer is where the mouse is in frame pixels. Hovering a range in the number line
+	// highlights its footprint in the main view, and so does selecting it with SelectedRange,
+	// which is -1 when nothing is selected.
+	Pointer       image.Point
+	SelectedRange int
+}
+
+type ColorMode int
+
+const (
+	// ColorByCurve colors each cell by its position along the curve, fully saturated where the
+	// ranges read it.
+	ColorByCurve ColorMode = iota
+	// ColorByRange colors the cells each range reads with that range's color from rangeColors,
+	// so it is clear which range covers which cells. The number line bars use the same colors.
+	ColorByRange
+)
+
+var colorModeNames = map[ColorMode]string{
+	ColorByCurve: "curve",
+	ColorByRange: "range",
+}
+
+// rangeColors are told apart easily, and are not too close to missedColor or highlightColor.
+var rangeColors = []color.RGBA{
+	{0xe6, 0x19, 0x4b, 0xff},
+	{0x3c, 0xb4, 0x4b, 0xff},
+	{0x43, 0x63, 0xd8, 0xff},
+	{0xf5, 0x82, 0x31, 0xff},
+	{0x91, 0x1e, 0xb4, 0xff},
+	{0x42, 0xd4, 0xf4, 0xff},
+	{0xbf, 0xef, 0x45, 0xff},
+	{0xfa, 0xbe, 0xd4, 0xff},
+	{0x46, 0x99, 0x90, 0xff},
+	{0xdc, 0xbe, 0xff, 0xff},
+	{0x9a, 0x63, 0x24, 0xff},
+	{0xaa, 0xff, 0xc3, 0xff},
+}
+
+// how long each range is shown when CycleRanges is on
+const rangeCycleSeconds = 1.0
+
+func rangeColor(index int) color.RGBA {
+	return rangeColors[index%len(rangeColors)]
+}
+
+// QueryStats describes how one query went.
+//
+// Every pixel of the curve view falls into one of these classes:
+//
+//	hit:    inside the rectangle and inside one of the ranges
+//	wasted: outside the rectangle but inside one of the ranges, this is the read amplification
+//	missed: inside the rectangle but outside every range. This should never happen, it means
+//	        the ranges don't cover the query.
+type QueryStats struct {
+	Rect       QueryRect
+	RangeCount int
+	HitArea    int
+	WastedArea int
+	MissedArea int
+}
+
+// FrameStats describes the queries that were visualized in a frame. The embedded QueryStats are
+// for the union of all of them: the bounding box of the rectangles, and their ranges merged and
+// deduplicated, against the pixels inside any rectangle. With a single query that is just the
+// query. Queries has the stats of each query on its own, Path first.
+type FrameStats struct {
+	QueryStats
+	Queries []QueryStats
+
+	// Compare is the stats of the right panel, when the frame is split to compare curves.
+	Compare *FrameStats
+}
+
+// query is one rectangle of a frame and the ranges the index returned for it.
+type query struct {
+	rect       QueryRect
+	screenRect image.Rectangle
+	color      color.RGBA
+	ranges     [][]uint64
+	set        rangeSet
+
+	// the rectangle in index space
+	minX, minY, maxX, maxY int
+}
+
+// wasted pixels are striped with the dim background so they stay distinguishable from hits
+// without relying on hue. Missed pixels are a magenta and black checkerboard.
+const wastedStripeWidth = 2
+
+var missedColor = color.RGBA{0xff, 0x00, 0xff, 0xff}
+var white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+var black = color.RGBA{0x00, 0x00, 0x00, 0xff}
+
+// queryColors are the outline colors of Path and ExtraPaths, in order. When there are several
+// queries, the cells only one of them reads are tinted with its color, and the cells more than
+// one of them read are tinted with overlapColor: those are the reads a merged batch saves.
+var queryColors = []color.RGBA{
+	white,
+	{0x00, 0xe5, 0xff, 0xff},
+	{0xff, 0x6e, 0x40, 0xff},
+	{0x76, 0xff, 0x03, 0xff},
+	{0x44, 0x8a, 0xff, 0xff},
+	{0xff, 0x80, 0xab, 0xff},
+}
+var overlapColor = color.RGBA{0xff, 0x20, 0x20, 0xff}
+
+// FrameGenerator renders the curve visualization into an image.RGBA without touching OpenGL,
+// so it can be displayed in a window or written straight to disk.
+type FrameGenerator struct {
+	// Workers is how many goroutines render each frame, in bands of rows. Anything below 2
+	// renders on the calling goroutine.
+	Workers int
+
+	curves        map[curveID]Curve
+	curveBuffer   curveBuffer
+	curveOverlays map[curveID][]curveCell
+
+	// compare renders the right panel of a split frame, with caches of its own
+	compare *FrameGenerator
+
+	// the number line and ranges of the last rendered frame, for RangeAt
+	numberLine numberLine
+	ranges     [][]uint64
+}
+
+func DefaultFrameParams() FrameParams {
+	return FrameParams{
+		Size:          dim,
+		CurveBits:     bits.UintSize,
+		Curve:         "hilbert",
+		IOPSCostParam: 1,
+		Path:          LissajousPath{},
+		View:          DefaultViewport(),
+		Pointer:       image.Point{-1, -1},
+		SelectedRange: -1,
+		Palette:       rainbowPalette{},
+		PaletteCycles: rainbowCount,
+		Inspect:       true,
+		PlotSeconds:   defaultPlotSeconds,
+	}
+}
+
+func NewFrameGenerator() *FrameGenerator {
+	return &FrameGenerator{
+		Workers:       runtime.GOMAXPROCS(0),
+		curves:        map[curveID]Curve{},
+		curveOverlays: map[curveID][]curveCell{},
+	}
+}
+
+func (generator *FrameGenerator) workers() int {
+	if generator.Workers < 1 {
+		return 1
+	}
+	return generator.Workers
+}
+
+// curveID is a curve at a bit width.
+type curveID struct {
+	name      string
+	curveBits int
+}
+
+// curve builds a curve the first time it is asked for, so switching back and forth at runtime is
+// cheap.
+func (generator *FrameGenerator) curve(name string, curveBits int) (Curve, error) {
+	id := curveID{name, curveBits}
+	curve, has := generator.curves[id]
+	if !has {
+		var err error
+		curve, err = newCurve(name, curveBits)
+		if err != nil {
+			return nil, err
+		}
+		generator.curves[id] = curve
+	}
+	return curve, nil
+}
+
+func (generator *FrameGenerator) Render(seconds float64, params FrameParams) (*image.RGBA, FrameStats, error) {
+	if params.CompareCurve != "" {
+		return generator.renderSplit(seconds, params)
+	}
+	return generator.render(seconds, params)
+}
+
+func (generator *FrameGenerator) render(seconds float64, params FrameParams) (*image.RGBA, FrameStats, error) {
+	curve, err := generator.curve(params.Curve, params.CurveBits)
+	if err != nil {
+		return nil, FrameStats{}, err
+	}
+	size := params.Size
+	view := params.View
+	if size <= numberLineHeight {
+		return nil, FrameStats{}, fmt.Errorf("a %d pixel frame has no room above the %d pixel number line", size, numberLineHeight)
+	}
+
+	inputMin, inputMax := curve.GetValidInputRange()
+	_, outputMaxBytes := curve.GetOutputRange()
+	curveLength := keyToUint64(outputMaxBytes)
+	//log.Printf("inputMin: %d, inputMax: %d, curveLength: %d", inputMin, inputMax, curveLength)
+
+	worldToIndex := func(fraction float64) int {
+		return int(lerp(float64(inputMin), float64(inputMax), fraction))
+	}
+
+	paths := params.Paths()
+	queries := make([]query, len(paths))
+	var allRanges [][]uint64
+	for i, path := range paths {
+		// paths and placed rectangles may reach past the world, but the index only accepts its input range
+		rect := path.RectAt(seconds, size).Canon().Clamp()
+		q := query{
+			rect:       rect,
+			screenRect: view.ScreenRect(rect, size),
+			color:      queryColors[i%len(queryColors)],
+			minX:       worldToIndex(rect.MinX),
+			minY:       worldToIndex(rect.MinY),
+			maxX:       worldToIndex(rect.MaxX),
+			maxY:       worldToIndex(rect.MaxY),
+		}
+
+		byteRanges, err := curve.RectangleToIndexedRanges(q.minX, q.minY, q.maxX-q.minX, q.maxY-q.minY, params.IOPSCostParam)
+		if err != nil {
+			return nil, FrameStats{}, err
+		}
+		q.ranges = make([][]uint64, len(byteRanges))
+		for j, byteRange := range byteRanges {
+			q.ranges[j] = []uint64{
+				keyToUint64(byteRange.Start),
+				keyToUint64(byteRange.End),
+			}
+		}
+		q.set = newRangeSet(q.ranges)
+		allRanges = append(allRanges, q.ranges...)
+		queries[i] = q
+	}
+
+	// the union is what a batch of the queries would read: every range once, overlapping and
+	// adjacent ones merged.
+	ranges := allRanges
+	if len(queries) > 1 {
+		merged := newRangeSet(allRanges)
+		ranges = make([][]uint64, len(merged.starts))
+		for i := range ranges {
+			ranges[i] = []uint64{merged.starts[i], merged.ends[i]}
+		}
+	}
+	rangeSet := newRangeSet(ranges)
+
+	numberLineTop := size - numberLineHeight
+	line := newNumberLine(ranges, 0, curveLength, curveLength, size)
+	highlighted := -1
+	focused := -1
+	if params.CycleRanges && len(ranges) > 0 {
+		focused = int(math.Floor(seconds/rangeCycleSeconds)) % len(ranges)
+		if focused < 0 {
+			focused += len(ranges)
+		}
+		highlighted = focused
+	} else if params.SelectedRange >= 0 && params.SelectedRange < len(ranges) {
+		highlighted = params.SelectedRange
+	} else if params.Pointer.In(image.Rect(0, numberLineTop, size, size)) {
+		highlighted = line.rangeAt(ranges, params.Pointer.X)
+	}
+	colorByRange := params.ColorMode == ColorByRange
+
+	// outlineAt returns the color of the outline at x,y, the last query's where they cross
+	outlineAt := func(x, y int) (color.RGBA, bool) {
+		for i := len(queries) - 1; i >= 0; i-- {
+			rect := queries[i].screenRect
+			onVertical := (x == rect.Max.X || x == rect.Min.X) && y >= rect.Min.Y && y <= rect.Max.Y
+			onHorizontal := (y == rect.Max.Y || y == rect.Min.Y) && x >= rect.Min.X && x <= rect.Max.X
+			if onVertical || onHorizontal {
+				return queries[i].color, true
+			}
+		}
+		return color.RGBA{}, false
+	}
+
+	buffer, err := generator.curvePoints(curve, curveBufferKey{
+		curve:     params.Curve,
+		curveBits: params.CurveBits,
+		size:      size,
+		height:    numberLineTop,
+		view:      view,
+	})
+	if err != nil {
+		return nil, FrameStats{}, err
+	}
+	// without any ranges to zoom to, the number line spans the part of the curve that is visible
+	// in the main view, so it zooms along with the viewport.
+	if len(ranges) == 0 && buffer.visibleMin <= buffer.visibleMax {
+		line = newNumberLine(nil, buffer.visibleMin, buffer.visibleMax, curveLength, size)
+	}
+
+	// the index space coordinates of each column and row, for telling whether a pixel is inside a rectangle
+	remappedXs := make([]int, size)
+	for x := range remappedXs {
+		worldX, _ := view.ScreenToWorld(float64(x), 0, size)
+		remappedXs[x] = worldToIndex(worldX)
+	}
+	remappedYs := make([]int, numberLineTop)
+	for y := range remappedYs {
+		_, worldY := view.ScreenToWorld(0, float64(y), size)
+		remappedYs[y] = worldToIndex(worldY)
+	}
+
+	rgba := image.NewRGBA(image.Rectangle{Min: image.Point{0, 0}, Max: image.Point{size, size}})
+	workers := generator.workers()
+	bandStats := make([][]QueryStats, workers)
+	forEachBand(numberLineTop, workers, func(band, minY, maxY int) {
+		// the union first, then each query
+		counts := make([]QueryStats, 1+len(queries))
+		bandStats[band] = counts
+		for y := minY; y < maxY; y++ {
+			for x := 0; x < size; x++ {
+
+				if outline, onOutline := outlineAt(x, y); onOutline {
+					setPixel(rgba, x, y, outline)
+					continue
+				}
+
+				remappedX := remappedXs[x]
+				remappedY := remappedYs[y]
+				curvePoint := buffer.points[y*size+x]
+
+				rangeIndex := rangeSet.indexOf(curvePoint)
+				inRange := rangeIndex != -1
+				inRect := false
+				readBy := 0
+				var readByColor color.RGBA
+				for i, q := range queries {
+					queryInRange := inRange
+					if len(queries) > 1 {
+						queryInRange = q.set.indexOf(curvePoint) != -1
+					}
+					queryInRect := remappedX >= q.minX && remappedX <= q.maxX && remappedY >= q.minY && remappedY <= q.maxY
+					counts[1+i].classify(queryInRange, queryInRect)
+					inRect = inRect || queryInRect
+					if queryInRange {
+						readBy++
+						readByColor = q.color
+					}
+				}
+				counts[0].classify(inRange, inRect)
+
+				curveFloat := curvePosition(curvePoint, curveLength)
+				//sat := (float64(2) + math.Sin(curveFloat*math.Pi*2*saturationFluctuationCount)) * float64(0.3333333)
+				bright := false
+				switch {
+				case inRange && inRect:
+					bright = true
+				case inRange:
+					if ((x+y)/wastedStripeWidth)%2 == 0 {
+						bright = true
+					}
+				case inRect:
+					if (x+y)%2 == 0 {
+						setPixel(rgba, x, y, missedColor)
+					} else {
+						setPixel(rgba, x, y, black)
+					}
+					continue
+				}
+				rainbow := params.Palette.Color(curveFloat*params.PaletteCycles, !bright)
+				if colorByRange && inRange {
+					rainbow = rangeColor(rangeIndex)
+					if !bright {
+						rainbow = mix(rainbow, black, 0.6)
+					}
+				} else if len(queries) > 1 && readBy == 1 {
+					rainbow = mix(rainbow, readByColor, 0.35)
+				} else if readBy > 1 {
+					rainbow = mix(rainbow, overlapColor, 0.5)
+				}
+				inHighlighted := highlighted >= 0 && curvePoint >= ranges[highlighted][0] && curvePoint <= ranges[highlighted][1]
+				if focused >= 0 && !inHighlighted {
+					rainbow = mix(rainbow, black, 0.75)
+				} else if inHighlighted && !(focused >= 0 && colorByRange) {
+					rainbow = mix(rainbow, highlightColor, 0.6)
+				}
+
+				setPixel(rgba, x, y, rainbow)
+			}
+		}
+	})
+
+	if params.CurveOverlay {
+		mainView := image.Rect(0, 0, size, numberLineTop)
+		if params.CurveBits > maxCurveOverlayBits {
+			drawText(rgba, hudMargin, numberLineTop-hudMargin-glyphHeight, fmt.Sprintf("THE CURVE OVERLAY NEEDS %d BITS OR FEWER", maxCurveOverlayBits), white, 1)
+		} else {
+			cells, err := generator.curveCells(curve, params.CurveBits)
+			if err != nil {
+				return nil, FrameStats{}, err
+			}
+			inputSpan := float64(inputMax) - float64(inputMin)
+			drawCurveOverlay(rgba, mainView, cells, func(cell curveCell) (float64, float64) {
+				return view.WorldToScreen((cell.x-float64(inputMin))/inputSpan, (cell.y-float64(inputMin))/inputSpan, size)
+			}, rangeSet)
+			// the rectangles stay on top
+			for y := 0; y < numberLineTop; y++ {
+				for x := 0; x < size; x++ {
+					if outline, onOutline := outlineAt(x, y); onOutline {
+						setPixel(rgba, x, y, outline)
+					}
+				}
+			}
+		}
+	}
+
+	var inspection *CellInspection
+	if params.Inspect {
+		pointer := params.Pointer
+		if pointer.In(image.Rect(0, 0, size, numberLineTop)) {
+			cell, err := generator.InspectPixel(params, pointer.X, pointer.Y)
+			if err != nil {
+				return nil, FrameStats{}, err
+			}
+			inspection = &cell
+		} else if pointer.In(image.Rect(0, numberLineTop, size, size)) {
+			for _, pixel := range generator.PixelsWithOffsets(line.bucket(pointer.X)) {
+				setPixel(rgba, pixel.X, pixel.Y, mix(rgba.RGBAAt(pixel.X, pixel.Y), white, 0.75))
+			}
+		}
+	}
+
+	line.draw(rgba, numberLineTop, ranges, highlighted, queries, colorByRange)
+	line.drawLegend(rgba, numberLineTop, curveLength, func(offset uint64) color.RGBA {
+		return params.Palette.Color(curvePosition(offset, curveLength)*params.PaletteCycles, false)
+	})
+	if inspection != nil {
+		line.drawMarker(rgba, numberLineTop, inspection.Offset, highlightColor)
+	}
+	generator.numberLine = line
+	generator.ranges = ranges
+
+	stats := FrameStats{Queries: make([]QueryStats, len(queries))}
+	stats.RangeCount = len(ranges)
+	for i, q := range queries {
+		stats.Queries[i].Rect = q.rect
+		stats.Queries[i].RangeCount = len(q.ranges)
+		if i == 0 {
+			stats.Rect = q.rect
+		} else {
+			stats.Rect = stats.Rect.Union(q.rect)
+		}
+	}
+	for _, counts := range bandStats {
+		if counts == nil {
+			continue
+		}
+		stats.QueryStats.add(counts[0])
+		for i := range queries {
+			stats.Queries[i].add(counts[1+i])
+		}
+	}
+
+	if params.HUD {
+		lines := []string{
+			fmt.Sprintf("FPS %.1f", params.FPS),
+			fmt.Sprintf("BITS %d  COST %g", params.CurveBits, params.IOPSCostParam),
+			fmt.Sprintf("RANGES %d  OVERSAMPLING %.2f", stats.RangeCount, stats.Oversampling()),
+		}
+		for i, q := range queries {
+			label := "RECT"
+			if len(queries) > 1 {
+				label = fmt.Sprintf("RECT %d", i)
+			}
+			lines = append(lines, fmt.Sprintf("%s X %d..%d Y %d..%d", label, q.minX, q.maxX, q.minY, q.maxY))
+		}
+		pointer := params.Pointer
+		if pointer.In(image.Rect(0, 0, size, numberLineTop)) {
+			lines = append(lines, fmt.Sprintf(
+				"CURSOR X %d Y %d CURVE %d",
+				remappedXs[pointer.X], remappedYs[pointer.Y], buffer.points[pointer.Y*size+pointer.X],
+			))
+		}
+		drawHUD(rgba, lines)
+	}
+	if inspection != nil {
+		drawInspection(rgba, image.Rect(0, 0, size, numberLineTop), inspection.Pixel, inspection.Lines())
+	}
+	return rgba, stats, nil
+}
+
+// Paths returns Path followed by ExtraPaths.
+func (params FrameParams) Paths() []RectPath {
+	return append([]RectPath{params.Path}, params.ExtraPaths...)
+}
+
+// placed returns params with its rectangles placed where they are at seconds in a frame of
+// params.Size, so they stay put when the frame is rendered as panels of another size.
+func (params FrameParams) placed(seconds float64) FrameParams {
+	paths := params.Paths()
+	for i, path := range paths {
+		paths[i] = placedPath{path.RectAt(seconds, params.Size)}
+	}
+	params.Path = paths[0]
+	params.ExtraPaths = paths[1:]
+	return params
+}
+
+func (stats *QueryStats) classify(inRange, inRect bool) {
+	switch {
+	case inRange && inRect:
+		stats.HitArea++
+	case inRange:
+		stats.WastedArea++
+	case inRect:
+		stats.MissedArea++
+	}
+}
+
+// add sums up the areas of other, which covers different pixels of the same query.
+func (stats *QueryStats) add(other QueryStats) {
+	stats.HitArea += other.HitArea
+	stats.WastedArea += other.WastedArea
+	stats.MissedArea += other.MissedArea
+}
+
+// RangeAt returns the index of the range under x in the number line panel of the last rendered
+// frame, or -1 if there is none.
+func (generator *FrameGenerator) RangeAt(x int) int {
+	return generator.numberLine.rangeAt(generator.ranges, x)
+}
+
+// Oversampling is the wasted area relative to the hit area, the same ratio the benchmark reports
+// as "average oversampling": 0 means the ranges cover exactly the rectangle, 1 means they cover
+// twice as much as needed.
+func (stats QueryStats) Oversampling() float64 {
+	if stats.HitArea == 0 {
+		if stats.WastedArea > 0 {
+			return 1
+		}
+		return 0
+	}
+	return float64(stats.WastedArea) / float64(stats.HitArea)
+}
+
+// CoveredArea is how many pixels the ranges read, whether they were needed or not.
+func (stats QueryStats) CoveredArea() int {
+	return stats.HitArea + stats.WastedArea
+}
+
+// statsLine is the line logged for a frame: enough to reproduce it and to see how the query went.
+func statsLine(frame int, seconds float64, params FrameParams, stats FrameStats) string {
+	line := fmt.Sprintf(
+		"frame %d, t: %.3f, rect: %s, bits: %d, iopsCostParam: %g, range count: %d, oversampling: %.2f",
+		frame, seconds, formatRect(stats.Rect, params.Size), params.CurveBits, params.IOPSCostParam, stats.RangeCount, stats.Oversampling(),
+	)
+	if params.Curve != "hilbert" {
+		line += fmt.Sprintf(", curve: %s", params.Curve)
+	}
+	if params.View != DefaultViewport() {
+		line += fmt.Sprintf(", view: %s", formatView(params.View))
+	}
+	if params.Palette.Name() != "rainbow" || params.PaletteCycles != rainbowCount {
+		line += fmt.Sprintf(", palette: %s x%g", params.Palette.Name(), params.PaletteCycles)
+	}
+	if params.ColorMode != ColorByCurve {
+		line += fmt.Sprintf(", color: %s", colorModeNames[params.ColorMode])
+	}
+	if len(stats.Queries) > 1 {
+		queryLines := make([]string, len(stats.Queries))
+		rangeReads := 0
+		for i, queryStats := range stats.Queries {
+			queryLines[i] = fmt.Sprintf(
+				"%d: %s %d ranges covering %d",
+				i, formatRect(queryStats.Rect, params.Size), queryStats.RangeCount, queryStats.CoveredArea(),
+			)
+			rangeReads += queryStats.RangeCount
+		}
+		line += fmt.Sprintf(
+			", queries: [%s], union: %d ranges covering %d, merging saves %d range reads",
+			strings.Join(queryLines, "; "), stats.RangeCount, stats.CoveredArea(), rangeReads-stats.RangeCount,
+		)
+	}
+	if stats.Compare != nil {
+		line += fmt.Sprintf(
+			", compare: %s %d ranges, oversampling: %.2f",
+			params.CompareCurve, stats.Compare.RangeCount, stats.Compare.Oversampling(),
+		)
+	}
+	if stats.MissedArea > 0 || (stats.Compare != nil && stats.Compare.MissedArea > 0) {
+		missed := stats.MissedArea
+		if stats.Compare != nil {
+			missed += stats.Compare.MissedArea
+		}
+		line += fmt.Sprintf(", MISSED %d pixels inside the rectangle", missed)
+	}
+	return line
+}
+
+// formatRect prints a rectangle the same way the -rect flag parses it, so a surprising frame can be
+// pinned down with -path fixed -rect <value>.
+func formatRect(rect QueryRect, size int) string {
+	pixels := rect.ToPixelRect(size)
+	return fmt.Sprintf("%s,%s,%s,%s", formatFloat(pixels.X), formatFloat(pixels.Y), formatFloat(pixels.Width), formatFloat(pixels.Height))
+}
+
+// formatView prints a viewport the same way the -view flag parses it.
+func formatView(view Viewport) string {
+	return fmt.Sprintf("%s,%s,%s", formatFloat(view.CenterX), formatFloat(view.CenterY), formatFloat(view.Zoom))
+}
+
+// formatFloat prints the shortest representation that parses back to the same value.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
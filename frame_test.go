@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testParams is a small frame, so the tests render quickly at any bit width.
+func testParams() FrameParams {
+	params := DefaultFrameParams()
+	params.Size = 128
+	params.Path = FixedPath{PixelRect{40, 50, 30, 20}}
+	return params
+}
+
+func TestParallelRenderMatchesSingleThreaded(t *testing.T) {
+	cases := map[string]func(params *FrameParams){
+		"default": func(params *FrameParams) {},
+		"animated": func(params *FrameParams) {
+			params.Path = LissajousPath{}
+		},
+		"several queries colored by range": func(params *FrameParams) {
+			params.ExtraPaths = []RectPath{FixedPath{PixelRect{60, 60, 25, 25}}}
+			params.ColorMode = ColorByRange
+		},
+		"zoomed in": func(params *FrameParams) {
+			params.View = Viewport{CenterX: 0.4, CenterY: 0.45, Zoom: 8}
+		},
+	}
+	for name, setup := range cases {
+		params := testParams()
+		setup(&params)
+		for _, seconds := range []float64{0, 1.5, 7} {
+			single := NewFrameGenerator()
+			single.Workers = 1
+			singleRGBA, singleStats, err := single.Render(seconds, params)
+			if err != nil {
+				t.Fatalf("%s at %gs: %s", name, seconds, err)
+			}
+
+			parallel := NewFrameGenerator()
+			parallel.Workers = 7
+			parallelRGBA, parallelStats, err := parallel.Render(seconds, params)
+			if err != nil {
+				t.Fatalf("%s at %gs: %s", name, seconds, err)
+			}
+
+			if !bytes.Equal(singleRGBA.Pix, parallelRGBA.Pix) {
+				t.Errorf("%s at %gs: the parallel frame differs from the single threaded one", name, seconds)
+			}
+			if !reflect.DeepEqual(singleStats, parallelStats) {
+				t.Errorf("%s at %gs: parallel stats %+v, single threaded %+v", name, seconds, parallelStats, singleStats)
+			}
+		}
+	}
+}
+
+func TestRenderRejectsFramesWithoutRoomAboveTheNumberLine(t *testing.T) {
+	for _, size := range []int{0, 30, numberLineHeight} {
+		params := testParams()
+		params.Size = size
+		_, _, err := NewFrameGenerator().Render(0, params)
+		if err == nil {
+			t.Errorf("a %d pixel frame should be rejected", size)
+		}
+	}
+}
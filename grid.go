@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	imagedraw "image/draw"
+	"image/png"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GridOptions controls the small multiples run_grid renders: one panel for every combination of
+// a bit width and a cost parameter, rows and columns in the order given.
+type GridOptions struct {
+	Output    string
+	CurveBits []int
+	Costs     []float32
+	PanelSize int
+	Seconds   float64
+}
+
+// the space between the panels of the grid
+const gridGap = 2
+
+// the smallest panel that still has room for the main view above the number line
+const minGridPanelSize = 96
+
+// run_grid renders the grid of panels into one PNG, for putting next to an explanation of what
+// the bit width and the cost parameter do.
+func run_grid(generator *FrameGenerator, params FrameParams, options GridOptions) error {
+	rgba, err := renderGrid(generator, params, options)
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(options.Output)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, rgba)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// renderGrid renders the same rectangles at every bit width (the rows) and cost parameter (the
+// columns), and labels each panel with its range count and oversampling.
+func renderGrid(generator *FrameGenerator, params FrameParams, options GridOptions) (*image.RGBA, error) {
+	rowLabels := make([]string, len(options.CurveBits))
+	labelWidth := 0
+	for i, curveBits := range options.CurveBits {
+		rowLabels[i] = fmt.Sprintf("%d BITS", curveBits)
+		if width := textWidth(rowLabels[i], 1); width > labelWidth {
+			labelWidth = width
+		}
+	}
+	labelWidth += hudMargin * 2
+	headerHeight := glyphHeight + hudMargin*2
+	step := options.PanelSize + gridGap
+
+	rgba := image.NewRGBA(image.Rect(0, 0, labelWidth+len(options.Costs)*step, headerHeight+len(options.CurveBits)*step))
+	imagedraw.Draw(rgba, rgba.Rect, &image.Uniform{black}, image.Point{}, imagedraw.Src)
+	for column, cost := range options.Costs {
+		drawText(rgba, labelWidth+column*step+hudMargin, hudMargin, fmt.Sprintf("COST %g", cost), white, 1)
+	}
+
+	placed := params.placed(options.Seconds)
+	placed.Size = options.PanelSize
+	placed.HUD = false
+	placed.Inspect = false
+	placed.Pointer = image.Point{-1, -1}
+	for row, curveBits := range options.CurveBits {
+		drawText(rgba, hudMargin, headerHeight+row*step+hudMargin, rowLabels[row], white, 1)
+		for column, cost := range options.Costs {
+			panelParams := placed
+			err := setCurveBits(&panelParams, curveBits)
+			if err != nil {
+				return nil, err
+			}
+			panelParams.IOPSCostParam = cost
+
+			panelRGBA, stats, err := generator.Render(options.Seconds, panelParams)
+			if err != nil {
+				return nil, err
+			}
+			panel := image.Rect(0, 0, options.PanelSize, options.PanelSize).Add(image.Point{labelWidth + column*step, headerHeight + row*step})
+			imagedraw.Draw(rgba, panel, panelRGBA, image.Point{}, imagedraw.Src)
+			drawTextBox(rgba, panel.Min.X+hudMargin, panel.Min.Y+hudMargin, []string{
+				fmt.Sprintf("%d RANGES", stats.RangeCount),
+				fmt.Sprintf("OVERSAMPLING %.2f", stats.Oversampling()),
+			})
+
+			// in the pixels of -rect, not of the panel
+			panelParams.Size = params.Size
+			log.Println(statsLine(0, options.Seconds, panelParams, stats))
+		}
+	}
+	return rgba, nil
+}
+
+// parseGridBits parses a comma separated list of bit widths, such as "8,16,32,64".
+func parseGridBits(value string) ([]int, error) {
+	var curveBits []int
+	for _, part := range strings.Split(value, ",") {
+		bitWidth, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("expected comma separated bit widths but got '%s': %s", value, err)
+		}
+		curveBits = append(curveBits, bitWidth)
+	}
+	return curveBits, nil
+}
+
+// parseGridCosts parses a comma separated list of cost parameters, such as "0.1,0.5,1,4".
+func parseGridCosts(value string) ([]float32, error) {
+	var costs []float32
+	for _, part := range strings.Split(value, ",") {
+		cost, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil || cost <= 0 || math.IsInf(cost, 0) || math.IsNaN(cost) {
+			return nil, fmt.Errorf("expected comma separated positive cost parameters but got '%s'", value)
+		}
+		costs = append(costs, float32(cost))
+	}
+	return costs, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGridCosts(t *testing.T) {
+	costs, err := parseGridCosts("0.1, 0.5,1,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float32{0.1, 0.5, 1, 4}; !reflect.DeepEqual(costs, expected) {
+		t.Errorf("expected %v, got %v", expected, costs)
+	}
+
+	for _, value := range []string{"", "1,,2", "0", "-1", "nan", "1,inf", "+Inf"} {
+		if costs, err := parseGridCosts(value); err == nil {
+			t.Errorf("'%s': expected an error, got %v", value, costs)
+		}
+	}
+}
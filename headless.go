@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+)
+
+// ExportOptions controls which frames run_headless renders and where they go.
+type ExportOptions struct {
+	Format       string
+	Output       string
+	FrameCount   int
+	FPS          float64
+	StartSeconds float64
+
+	// CSV is where to write the range count, oversampling and rectangle area of every frame, if set.
+	CSV string
+}
+
+// run_headless renders FrameCount frames at a fixed timestep and hands each one to a FrameWriter
+// for the requested format. It never touches GLFW or OpenGL, so it works on machines without a
+// GPU or a display.
+func run_headless(generator *FrameGenerator, params FrameParams, options ExportOptions) error {
+	writer, err := newFrameWriter(options.Format, options.Output, options.FPS, params.Palette)
+	if err != nil {
+		return err
+	}
+
+	params.FPS = options.FPS
+	clock := SteppedClock{Start: options.StartSeconds, FPS: options.FPS}
+	history := History{KeepAll: options.CSV != ""}
+	for i := 0; i < options.FrameCount; i++ {
+		seconds := clock.Seconds(i)
+		rgba, stats, err := generator.Render(seconds, params)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+
+		history.Add(i, seconds, params, stats)
+		if params.Plot {
+			drawPlot(rgba, history.Last(params.PlotSeconds), params.PlotSeconds)
+		}
+
+		err = writer.WriteFrame(rgba)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+
+		log.Println(statsLine(i, seconds, params, stats))
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	if options.CSV != "" {
+		return history.WriteCSV(options.CSV)
+	}
+	return nil
+}
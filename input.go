@@ -0,0 +1,208 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+type PointerEventKind int
+
+const (
+	PointerMove PointerEventKind = iota
+	PointerDown
+	PointerUp
+	PointerScroll
+)
+
+type PointerButton int
+
+const (
+	PrimaryButton PointerButton = iota
+	SecondaryButton
+)
+
+// PointerEvent is a window-system independent mouse event. The window system delivers it in
+// frame pixels, and Viewport.PointerToWorld converts it to world coordinates for the RectEditor.
+// Radius is how far from X,Y still counts as touching something, in the same units.
+// Button is only meaningful for PointerDown and PointerUp, Scroll only for PointerScroll.
+type PointerEvent struct {
+	Kind   PointerEventKind
+	Button PointerButton
+	X      float64
+	Y      float64
+	Radius float64
+	Scroll float64
+}
+
+type editMode int
+
+const (
+	editIdle editMode = iota
+	editDrawing
+	editMoving
+	editResizing
+)
+
+// how close (in frame pixels) the pointer has to be to a corner to grab it for resizing
+const cornerGrabDistance = 4
+
+// RectEditor is the state machine behind interactive rectangle editing. It only consumes
+// PointerEvents in world coordinates, so it does not care whether they came from GLFW or
+// somewhere else, or how the view is zoomed.
+//
+// Pressing the primary button on a corner resizes the rectangle, pressing inside it moves it, and
+// pressing anywhere else draws a new one. The secondary button hands the rectangle back to the
+// Fallback path.
+//
+// RectEditor is also a RectPath: until the user touches the rectangle it follows Fallback, and
+// from then on it stays wherever the user left it. It never leaves the world, even when the pointer
+// is dragged out of the window.
+type RectEditor struct {
+	Fallback RectPath
+	Rect     QueryRect
+	Edited   bool
+
+	mode     editMode
+	anchorX  float64
+	anchorY  float64
+	lastX    float64
+	lastY    float64
+	previous QueryRect
+	shown    QueryRect
+}
+
+func NewRectEditor(fallback RectPath) *RectEditor {
+	return &RectEditor{Fallback: fallback}
+}
+
+func (editor *RectEditor) RectAt(seconds float64, size int) QueryRect {
+	if !editor.Edited {
+		editor.shown = editor.Fallback.RectAt(seconds, size)
+		return editor.shown
+	}
+	return editor.Rect
+}
+
+func (editor *RectEditor) HandlePointer(event PointerEvent) {
+	switch event.Kind {
+	case PointerDown:
+		if event.Button == SecondaryButton {
+			editor.Release()
+			return
+		}
+		if event.Button != PrimaryButton || editor.mode != editIdle {
+			return
+		}
+		if !editor.Edited {
+			// grab the animated rectangle right where it is
+			editor.Rect = editor.shown
+		}
+		editor.previous = editor.Rect
+		editor.lastX, editor.lastY = event.X, event.Y
+
+		event.X, event.Y = clampUnit(event.X), clampUnit(event.Y)
+		if cornerX, cornerY, ok := editor.oppositeCorner(event); ok {
+			editor.mode = editResizing
+			editor.anchorX, editor.anchorY = cornerX, cornerY
+		} else if editor.Rect.Contains(event.X, event.Y) {
+			editor.mode = editMoving
+		} else {
+			editor.mode = editDrawing
+			editor.anchorX, editor.anchorY = event.X, event.Y
+			editor.Rect = QueryRect{event.X, event.Y, event.X, event.Y}
+		}
+		editor.Edited = true
+
+	case PointerMove:
+		switch editor.mode {
+		case editDrawing, editResizing:
+			editor.Rect = QueryRect{editor.anchorX, editor.anchorY, event.X, event.Y}.Canon().Clamp()
+		case editMoving:
+			editor.Rect = editor.Rect.Add(event.X-editor.lastX, event.Y-editor.lastY).KeepInside()
+			editor.lastX, editor.lastY = event.X, event.Y
+		}
+
+	case PointerUp:
+		if event.Button != PrimaryButton || editor.mode == editIdle {
+			return
+		}
+		editor.HandlePointer(PointerEvent{Kind: PointerMove, X: event.X, Y: event.Y})
+		if editor.mode == editDrawing && editor.Rect.Empty() {
+			// a click without a drag should not leave an invisible rectangle behind
+			editor.Rect = editor.previous
+		}
+		editor.mode = editIdle
+	}
+}
+
+// Nudge moves the rectangle by dx,dy and pushes each of its edges out by grow (or pulls them in,
+// when grow is negative), all in world coordinates. It is how the keyboard edits the rectangle.
+// A nudge that would turn the rectangle inside out is ignored.
+func (editor *RectEditor) Nudge(dx, dy, grow float64) {
+	if !editor.Edited {
+		editor.Rect = editor.shown
+		editor.Edited = true
+	}
+	rect := editor.Rect.Add(dx, dy).KeepInside()
+	rect = QueryRect{rect.MinX - grow, rect.MinY - grow, rect.MaxX + grow, rect.MaxY + grow}.Clamp()
+	if !rect.Empty() {
+		editor.Rect = rect
+	}
+}
+
+// Release hands the rectangle back to the Fallback path.
+func (editor *RectEditor) Release() {
+	editor.mode = editIdle
+	editor.Edited = false
+}
+
+// oppositeCorner returns the corner across from the one the pointer is grabbing, if any.
+func (editor *RectEditor) oppositeCorner(event PointerEvent) (float64, float64, bool) {
+	rect := editor.Rect
+	corners := [][2]float64{
+		{rect.MinX, rect.MinY},
+		{rect.MaxX, rect.MinY},
+		{rect.MaxX, rect.MaxY},
+		{rect.MinX, rect.MaxY},
+	}
+	for i, corner := range corners {
+		if math.Abs(event.X-corner[0]) <= event.Radius && math.Abs(event.Y-corner[1]) <= event.Radius {
+			opposite := corners[(i+2)%4]
+			return opposite[0], opposite[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// handleScreenPointer routes a pointer event in frame pixels: the scroll wheel zooms the view
+// around the pointer, clicking a range in the number line selects it (clicking it again or right
+// clicking the number line deselects it), and everything else edits the rectangle in world
+// coordinates.
+func handleScreenPointer(generator *FrameGenerator, params *FrameParams, editor *RectEditor, event PointerEvent) {
+	if params.CompareCurve != "" {
+		handleSplitPointer(generator, params, editor, event)
+		return
+	}
+	if event.Kind == PointerScroll {
+		params.View.ZoomAround(event.X, event.Y, math.Pow(scrollZoomFactor, event.Scroll), params.Size)
+		return
+	}
+
+	point := image.Point{int(math.Floor(event.X)), int(math.Floor(event.Y))}
+	if event.Kind == PointerMove {
+		params.Pointer = point
+	}
+	if event.Kind == PointerDown && point.Y >= params.Size-numberLineHeight {
+		selected := -1
+		if event.Button == PrimaryButton {
+			selected = generator.RangeAt(point.X)
+		}
+		if selected == params.SelectedRange {
+			selected = -1
+		}
+		params.SelectedRange = selected
+		return
+	}
+
+	editor.HandlePointer(params.View.PointerToWorld(event, params.Size))
+}
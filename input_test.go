@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRectEditor(t *testing.T) {
+	start := QueryRect{0.2, 0.2, 0.4, 0.4}
+	fallback := FixedPath{PixelRect{10, 10, 20, 20}}
+
+	down := func(x, y float64) PointerEvent {
+		return PointerEvent{Kind: PointerDown, Button: PrimaryButton, X: x, Y: y, Radius: 0.01}
+	}
+	move := func(x, y float64) PointerEvent {
+		return PointerEvent{Kind: PointerMove, X: x, Y: y}
+	}
+	up := func(x, y float64) PointerEvent {
+		return PointerEvent{Kind: PointerUp, Button: PrimaryButton, X: x, Y: y}
+	}
+
+	cases := []struct {
+		name   string
+		events []PointerEvent
+		nudges [][3]float64
+		rect   QueryRect
+		edited bool
+	}{
+		{
+			name:   "drawing a new rectangle",
+			events: []PointerEvent{down(0.7, 0.8), move(0.6, 0.9), up(0.5, 0.95)},
+			rect:   QueryRect{0.5, 0.8, 0.7, 0.95},
+			edited: true,
+		},
+		{
+			name:   "moving",
+			events: []PointerEvent{down(0.3, 0.3), move(0.35, 0.3), up(0.4, 0.25)},
+			rect:   QueryRect{0.3, 0.15, 0.5, 0.35},
+			edited: true,
+		},
+		{
+			name:   "resizing by a corner",
+			events: []PointerEvent{down(0.4, 0.4), move(0.5, 0.6), up(0.6, 0.45)},
+			rect:   QueryRect{0.2, 0.2, 0.6, 0.45},
+			edited: true,
+		},
+		{
+			name:   "resizing past the opposite corner",
+			events: []PointerEvent{down(0.2, 0.2), up(0.5, 0.1)},
+			rect:   QueryRect{0.4, 0.1, 0.5, 0.4},
+			edited: true,
+		},
+		{
+			name:   "a click without a drag keeps the previous rectangle",
+			events: []PointerEvent{down(0.8, 0.8), up(0.8, 0.8)},
+			rect:   start,
+			edited: true,
+		},
+		{
+			name:   "drawing past the edge of the world",
+			events: []PointerEvent{down(0.7, 0.8), move(1.4, 1.2), up(1.5, 1.3)},
+			rect:   QueryRect{0.7, 0.8, 1, 1},
+			edited: true,
+		},
+		{
+			name:   "moving past the edge of the world",
+			events: []PointerEvent{down(0.3, 0.3), up(1.3, 0.3)},
+			rect:   QueryRect{0.8, 0.2, 1, 0.4},
+			edited: true,
+		},
+		{
+			name: "the secondary button hands the rectangle back",
+			events: []PointerEvent{
+				down(0.7, 0.8), up(0.9, 0.9),
+				{Kind: PointerDown, Button: SecondaryButton, X: 0.5, Y: 0.5},
+				{Kind: PointerUp, Button: SecondaryButton, X: 0.5, Y: 0.5},
+			},
+			rect:   fallback.RectAt(0, 100),
+			edited: false,
+		},
+		{
+			name:   "nudging",
+			nudges: [][3]float64{{0.1, 0, 0}, {0, -0.05, 0}, {0, 0, 0.05}},
+			rect:   QueryRect{0.25, 0.1, 0.55, 0.4},
+			edited: true,
+		},
+		{
+			name:   "nudging past the edge of the world",
+			nudges: [][3]float64{{-0.5, 0, 0}, {0, 0, 0.3}},
+			rect:   QueryRect{0, 0, 0.5, 0.7},
+			edited: true,
+		},
+		{
+			name:   "shrinking inside out is ignored",
+			nudges: [][3]float64{{0, 0, -0.1}, {0, 0, -0.01}},
+			rect:   QueryRect{0.21, 0.21, 0.39, 0.39},
+			edited: true,
+		},
+	}
+
+	for _, c := range cases {
+		editor := NewRectEditor(fallback)
+		editor.Rect = start
+		editor.Edited = true
+		for _, event := range c.events {
+			editor.HandlePointer(event)
+		}
+		for _, nudge := range c.nudges {
+			editor.Nudge(nudge[0], nudge[1], nudge[2])
+		}
+
+		rect := editor.RectAt(0, 100)
+		if !closeRect(rect, c.rect) || editor.Edited != c.edited {
+			t.Errorf("%s: expected %+v (edited: %t), got %+v (edited: %t)", c.name, c.rect, c.edited, rect, editor.Edited)
+		}
+	}
+}
+
+// closeRect compares rectangles built from float arithmetic.
+func closeRect(a, b QueryRect) bool {
+	const epsilon = 1e-9
+	near := func(x, y float64) bool {
+		return x-y < epsilon && y-x < epsilon
+	}
+	return near(a.MinX, b.MinX) && near(a.MinY, b.MinY) && near(a.MaxX, b.MaxX) && near(a.MaxY, b.MaxY)
+}
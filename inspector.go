@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// CellInspection is what the inspector shows about the cell under a pixel of the main view.
+type CellInspection struct {
+	Pixel image.Point
+
+	// IndexX and IndexY are the cell in index space, Key is what GetIndexedPoint returned for it,
+	// Offset is Key decoded into a position along the curve, and Position is how far along the
+	// curve that is, from 0 to 1.
+	IndexX   int
+	IndexY   int
+	Key      []byte
+	Offset   uint64
+	Position float64
+}
+
+// InspectPixel looks up the cell under pixel x,y of the main view, for the curve, view and bit
+// width in params. It maps the pixel exactly the way Render does, so Offset is the curve value the
+// pixel is colored by.
+func (generator *FrameGenerator) InspectPixel(params FrameParams, x, y int) (CellInspection, error) {
+	curve, err := generator.curve(params.Curve, params.CurveBits)
+	if err != nil {
+		return CellInspection{}, err
+	}
+	inputMin, inputMax := curve.GetValidInputRange()
+	worldX, worldY := params.View.ScreenToWorld(float64(x), float64(y), params.Size)
+	inspection := CellInspection{
+		Pixel:  image.Point{x, y},
+		IndexX: int(lerp(float64(inputMin), float64(inputMax), worldX)),
+		IndexY: int(lerp(float64(inputMin), float64(inputMax), worldY)),
+	}
+	inspection.Key, err = curve.GetIndexedPoint(inspection.IndexX, inspection.IndexY)
+	if err != nil {
+		return CellInspection{}, err
+	}
+	inspection.Offset = keyToUint64(inspection.Key)
+	_, maxKey := curve.GetOutputRange()
+	inspection.Position = keyFraction(inspection.Key, maxKey)
+	return inspection, nil
+}
+
+// Lines is the inspection as the text shown next to the cursor.
+func (inspection CellInspection) Lines() []string {
+	return []string{
+		fmt.Sprintf("X %d Y %d", inspection.IndexX, inspection.IndexY),
+		fmt.Sprintf("KEY %X", inspection.Key),
+		fmt.Sprintf("OFFSET %d (%.4f%%)", inspection.Offset, inspection.Position*100),
+	}
+}
+
+// OffsetsAt returns the span of curve offsets under column x of the number line panel of the last
+// rendered frame: the bucket of the curve that column stands for.
+func (generator *FrameGenerator) OffsetsAt(x int) (uint64, uint64) {
+	return generator.numberLine.bucket(x)
+}
+
+// PixelsWithOffsets returns the pixels of the main view of the last rendered frame whose curve
+// value is between start and end, inclusive.
+func (generator *FrameGenerator) PixelsWithOffsets(start, end uint64) []image.Point {
+	buffer := &generator.curveBuffer
+	if !buffer.initialized {
+		return nil
+	}
+	var pixels []image.Point
+	for i, point := range buffer.points {
+		if point >= start && point <= end {
+			pixels = append(pixels, image.Point{i % buffer.key.size, i / buffer.key.size})
+		}
+	}
+	return pixels
+}
+
+// how far the inspector text is from the cursor
+const inspectorOffset = 10
+
+// drawInspection draws the lines next to the cursor, on whichever side keeps them inside area.
+func drawInspection(rgba *image.RGBA, area image.Rectangle, pointer image.Point, lines []string) {
+	width, height := textBoxSize(lines)
+	x := pointer.X + inspectorOffset
+	if x+width > area.Max.X {
+		x = pointer.X - inspectorOffset - width
+	}
+	y := pointer.Y + inspectorOffset
+	if y+height > area.Max.Y {
+		y = pointer.Y - inspectorOffset - height
+	}
+	drawTextBox(rgba, x, y, lines)
+}
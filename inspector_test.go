@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestInspectPixelMatchesRender(t *testing.T) {
+	for _, view := range []Viewport{DefaultViewport(), {CenterX: 0.3, CenterY: 0.6, Zoom: 5}} {
+		params := testParams()
+		params.View = view
+		generator := NewFrameGenerator()
+		_, _, err := generator.Render(0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		size := params.Size
+		for _, pixel := range [][2]int{{0, 0}, {17, 5}, {64, 64}, {size - 1, 20}, {3, size - numberLineHeight - 1}} {
+			inspection, err := generator.InspectPixel(params, pixel[0], pixel[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := generator.curveBuffer.points[pixel[1]*size+pixel[0]]
+			if inspection.Offset != rendered {
+				t.Errorf("view %+v, pixel %v: inspected offset %d, rendered %d", view, pixel, inspection.Offset, rendered)
+			}
+		}
+	}
+}
+
+func TestPixelsWithOffsetsStayInBucket(t *testing.T) {
+	params := testParams()
+	generator := NewFrameGenerator()
+	_, _, err := generator.Render(0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := params.Size
+	found := 0
+	for _, x := range []int{0, 10, 50, 51, size / 2, size - 1} {
+		start, end := generator.OffsetsAt(x)
+		pixels := generator.PixelsWithOffsets(start, end)
+		found += len(pixels)
+		for _, pixel := range pixels {
+			point := generator.curveBuffer.points[pixel.Y*size+pixel.X]
+			if point < start || point > end {
+				t.Errorf("column %d is offsets %d to %d, but pixel %v is at %d", x, start, end, pixel, point)
+			}
+		}
+
+		expected := 0
+		for _, point := range generator.curveBuffer.points {
+			if point >= start && point <= end {
+				expected++
+			}
+		}
+		if len(pixels) != expected {
+			t.Errorf("column %d: expected %d pixels, got %d", x, expected, len(pixels))
+		}
+	}
+	if found == 0 {
+		t.Error("none of the columns led to any pixels")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/big"
+)
+
+// The library returns keys as big endian byte strings as long as the curve needs: 1 byte for an
+// 8 bit curve, 8 for a 64 bit one. The demo works with curve offsets as uint64, which holds any of
+// them. A key longer than 8 bytes would be cut down to its leading 8 bytes, which keeps keys in
+// order and keeps their positions along the curve.
+
+// keyToUint64 reads a big endian key of any length. Keys shorter than 8 bytes, which
+// binary.BigEndian.Uint64 would panic on, are padded at the front.
+func keyToUint64(key []byte) uint64 {
+	if len(key) >= 8 {
+		return binary.BigEndian.Uint64(key)
+	}
+	padded := make([]byte, 8)
+	copy(padded[8-len(key):], key)
+	return binary.BigEndian.Uint64(padded)
+}
+
+// keyFraction is how far along the curve key is, from 0 at the start to 1 at maxKey, the end of
+// the output range. It is exact for keys of any length.
+func keyFraction(key, maxKey []byte) float64 {
+	max := new(big.Int).SetBytes(maxKey)
+	if max.Sign() == 0 {
+		return 0
+	}
+	fraction, _ := new(big.Rat).SetFrac(new(big.Int).SetBytes(key), max).Float64()
+	return fraction
+}
+
+// curvePosition is keyFraction for offsets that were already decoded with keyToUint64, where
+// curveLength is the decoded end of the output range. It is cheap enough to call for every pixel.
+func curvePosition(point, curveLength uint64) float64 {
+	if curveLength == 0 {
+		return 0
+	}
+	return float64(point) / float64(curveLength)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKeyToUint64(t *testing.T) {
+	cases := []struct {
+		key      []byte
+		expected uint64
+	}{
+		{[]byte{0x12, 0x34}, 0x1234},
+		{[]byte{0xff, 0xff}, 0xffff},
+		{[]byte{0x00, 0x00, 0x01, 0x00}, 0x100},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, 0xffffffff},
+		{[]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, 0x0102030405060708},
+		{[]byte{0x80, 0, 0, 0, 0, 0, 0, 0x05}, 1<<63 + 5},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, math.MaxUint64},
+	}
+	for _, c := range cases {
+		if value := keyToUint64(c.key); value != c.expected {
+			t.Errorf("%X: expected %d, got %d", c.key, c.expected, value)
+		}
+	}
+}
+
+func TestKeyFractionAndCurvePosition(t *testing.T) {
+	cases := []struct {
+		key      []byte
+		maxKey   []byte
+		expected float64
+	}{
+		{[]byte{0x00, 0x00}, []byte{0xff, 0xff}, 0},
+		{[]byte{0x80, 0x00}, []byte{0xff, 0xfe}, 0.5},
+		{[]byte{0xff, 0xff}, []byte{0xff, 0xff}, 1},
+		{[]byte{0x40, 0x00, 0x00, 0x00}, []byte{0xff, 0xff, 0xff, 0xff}, 0.25},
+		{[]byte{0x80, 0, 0, 0, 0, 0, 0, 0}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0.5},
+		{[]byte{0xc0, 0, 0, 0, 0, 0, 0, 0}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0.75},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1},
+		{[]byte{0x12, 0x34}, []byte{0x00, 0x00}, 0},
+	}
+	for _, c := range cases {
+		fraction := keyFraction(c.key, c.maxKey)
+		if math.Abs(fraction-c.expected) > 1e-4 {
+			t.Errorf("keyFraction(%X, %X): expected %g, got %g", c.key, c.maxKey, c.expected, fraction)
+		}
+		position := curvePosition(keyToUint64(c.key), keyToUint64(c.maxKey))
+		if math.Abs(position-fraction) > 1e-9 {
+			t.Errorf("curvePosition(%X, %X) is %g, but keyFraction is %g", c.key, c.maxKey, position, fraction)
+		}
+		if position < 0 || position > 1 {
+			t.Errorf("curvePosition(%X, %X) is %g, outside of 0 to 1", c.key, c.maxKey, position)
+		}
+	}
+}
+
+func TestRenderAtEveryCurveBits(t *testing.T) {
+	for _, curveBits := range curveBitsSteps {
+		params := testParams()
+		params.CurveBits = curveBits
+		rgba, stats, err := NewFrameGenerator().Render(0, params)
+		if err != nil {
+			t.Fatalf("%d bits: %s", curveBits, err)
+		}
+		if rgba.Rect.Dx() != params.Size || rgba.Rect.Dy() != params.Size {
+			t.Errorf("%d bits: expected a %dx%d frame, got %v", curveBits, params.Size, params.Size, rgba.Rect)
+		}
+		if stats.RangeCount == 0 {
+			t.Errorf("%d bits: the query should read at least one range", curveBits)
+		}
+	}
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
-	"encoding/binary"
+	"flag"
 	"fmt"
-	"image"
 	"image/color"
+	"io"
+	"log"
 	"math"
 	"math/bits"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
-
-	spatial "git.sequentialread.com/forest/modular-spatial-index"
 )
 
 const dim = 512
@@ -17,132 +20,197 @@ const dim = 512
 const rainbowCount = float64(20)
 const saturationFluctuationCount = float64(8)
 
-var frames = 0
-
 func main() {
-	spatialIndex, err := spatial.NewSpatialIndex2D(bits.UintSize)
+	headless := flag.Bool("headless", false, "export frames to disk instead of opening an OpenGL window")
+	exportOptions := ExportOptions{}
+	flag.StringVar(&exportOptions.Format, "format", "png", "export format in -headless mode: png (numbered sequence), gif or y4m")
+	flag.StringVar(&exportOptions.Output, "out", "frames", "output directory for png, or output file for gif and y4m (- for stdout)")
+	flag.IntVar(&exportOptions.FrameCount, "frames", 60, "number of frames to render in -headless mode")
+	flag.Float64Var(&exportOptions.FPS, "fps", 30, "animation frames per second in -headless mode and with -clock stepped, and the frame rate of -http and -terminal")
+	flag.Float64Var(&exportOptions.StartSeconds, "start", 0, "animation time in seconds of the first frame in -headless mode and with -clock stepped")
+	grid := flag.Bool("grid", false, "in -headless mode, write one PNG to -out with a panel of the frame at -start for every -grid-bits and -grid-costs")
+	gridBits := flag.String("grid-bits", "8,16,32,64", "comma separated bit widths of the rows of -grid")
+	gridCosts := flag.String("grid-costs", "0.1,0.5,1,4", "comma separated iopsCostParams of the columns of -grid")
+	gridSize := flag.Int("grid-size", 256, "width and height of each panel of -grid in pixels")
+	flag.StringVar(&exportOptions.CSV, "csv", "", "file to write the range count, oversampling and rectangle area of every frame to on exit, - for stdout")
+	plot := flag.Bool("plot", false, "chart the range count, oversampling and rectangle area over time above the number line (toggle at runtime with y)")
+	plotSeconds := flag.Float64("plot-seconds", defaultPlotSeconds, "how many seconds of animation time -plot shows")
+	clockName := flag.String("clock", "wall", "animation clock for the OpenGL window: wall (system time) or stepped (fixed 1/fps per frame from -start)")
+	pathName := flag.String("path", "lissajous", "how the query rectangle moves: lissajous, aspect (sweeps the aspect ratio), fixed or keyframes")
+	rect := flag.String("rect", "200,200,30,30", "x,y,width,height in screen pixels for -path fixed")
+	keyframesFile := flag.String("keyframes", "", "JSON keyframes file for -path keyframes")
+	curveBits := flag.Int("bits", bits.UintSize, "curve bit width passed to NewSpatialIndex2D (change at runtime with - and =, or 'bits <value>' on stdin)")
+	iopsCostParam := flag.Float64("cost", 1, "iopsCostParam passed to RectangleToIndexedRanges (change at runtime with [ and ], or 'cost <value>' on stdin)")
+	selectedRange := flag.String("select", "none", "index of the range whose footprint is highlighted, or none")
+	view := flag.String("view", "0.5,0.5,1", "x,y,zoom: the world position (0 to 1 on both axes) the view is centered on, and how far it is zoomed in")
+	httpAddress := flag.String("http", "", "address such as :8080 to serve a browser viewer on, alongside the OpenGL window")
+	window := flag.Bool("window", true, "open the OpenGL window, use -window=false with -http to only serve the browser viewer")
+	terminal := flag.Bool("terminal", false, "draw the demo in this terminal with 24 bit colors instead of opening the OpenGL window")
+	colorMode := flag.String("color", "curve", "what the colors show: curve (position along the curve) or range (which range reads each cell, change at runtime with c)")
+	cycle := flag.Bool("cycle", false, "show the ranges one at a time, a second each, dimming everything else (toggle at runtime with v)")
+	palette := flag.String("palette", "rainbow", "palette the curve is colored with: "+strings.Join(paletteNames, ", ")+" (change at runtime with p)")
+	paletteCycles := flag.Float64("cycles", rainbowCount, "how many times the palette repeats along the curve (change at runtime with , and .)")
+	hud := flag.Bool("hud", false, "draw the stats into the frame, in the window and in exports (toggle at runtime with h)")
+	curve := flag.Bool("curve", false, fmt.Sprintf("draw the curve as a line through its cells, with -bits %d or fewer (toggle at runtime with g)", maxCurveOverlayBits))
+	mapping := flag.String("mapping", "hilbert", "curve the cells are ordered by: "+strings.Join(curveNames, ", ")+" (change at runtime with m)")
+	compare := flag.String("compare", "off", "a second curve to show the same rectangles on, side by side, or off (change at runtime with n)")
+	var queries stringList
+	flag.Var(&queries, "query", "x,y,width,height in screen pixels of another fixed rectangle to query in the same batch, can be repeated")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "number of goroutines rendering each frame, 1 renders single threaded")
+	flag.Parse()
+
+	generator := NewFrameGenerator()
+	generator.Workers = *workers
+	params := DefaultFrameParams()
+	var err error
+	params.Path, err = newRectPath(*pathName, *rect, *keyframesFile)
 	if err != nil {
 		panic(err)
 	}
-	run_opengl_app(func() *image.RGBA {
-
-		seconds := float64(time.Now().UnixNano()) / float64(int64(time.Second))
+	commands := []string{fmt.Sprintf("bits %d", *curveBits), fmt.Sprintf("cost %g", *iopsCostParam), "view " + *view, "select " + *selectedRange, "color " + *colorMode, "palette " + *palette, fmt.Sprintf("cycles %g", *paletteCycles), "mapping " + *mapping, "compare " + *compare}
+	if *cycle {
+		commands = append(commands, "cycle on")
+	}
+	if *hud {
+		commands = append(commands, "hud on")
+	}
+	if *curve {
+		commands = append(commands, "curve on")
+	}
+	if *plot {
+		commands = append(commands, "plot on")
+	}
+	if exportOptions.FPS <= 0 {
+		panic(fmt.Sprintf("-fps must be positive, got %g", exportOptions.FPS))
+	}
+	if *plotSeconds <= 0 {
+		panic(fmt.Sprintf("-plot-seconds must be positive, got %g", *plotSeconds))
+	}
+	params.PlotSeconds = *plotSeconds
+	for _, query := range queries {
+		commands = append(commands, "query add "+query)
+	}
+	for _, command := range commands {
+		err = applyCommand(&params, command)
+		if err != nil {
+			panic(err)
+		}
+	}
 
-		rectX := int(float64(dim) * (float64(0.4) + math.Sin(seconds*float64(1.3))*float64(0.3)))
-		rectY := int(float64(dim) * (float64(0.5) + math.Cos(seconds*float64(0.3))*float64(0.2)))
-		rectSize := 1 + int(float64(25)*(float64(1)+math.Sin(seconds*float64(0.843))))
-		rectMaxX := rectX + rectSize
-		rectMaxY := rectY + rectSize
+	if *headless && *grid {
+		if *gridSize < minGridPanelSize {
+			panic(fmt.Sprintf("-grid-size must be at least %d, got %d", minGridPanelSize, *gridSize))
+		}
+		options := GridOptions{Output: exportOptions.Output, PanelSize: *gridSize, Seconds: exportOptions.StartSeconds}
+		options.CurveBits, err = parseGridBits(*gridBits)
+		if err != nil {
+			panic(err)
+		}
+		options.Costs, err = parseGridCosts(*gridCosts)
+		if err != nil {
+			panic(err)
+		}
+		err = run_grid(generator, params, options)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
 
-		inputMin, inputMax := spatialIndex.GetValidInputRange()
-		_, outputMaxBytes := spatialIndex.GetOutputRange()
-		curveLength := int(binary.BigEndian.Uint64(outputMaxBytes))
-		//log.Printf("inputMin: %d, inputMax: %d, curveLength: %d", inputMin, inputMax, curveLength)
+	if *headless {
+		err := run_headless(generator, params, exportOptions)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
 
-		remappedRectXMin := int(lerp(float64(inputMin), float64(inputMax), float64(rectX)/float64(dim)))
-		remappedRectYMin := int(lerp(float64(inputMin), float64(inputMax), float64(rectY)/float64(dim)))
-		remappedRectXMax := int(lerp(float64(inputMin), float64(inputMax), float64(rectX+rectSize)/float64(dim)))
-		remappedRectSize := remappedRectXMax - remappedRectXMin
+	var clock Clock = WallClock{}
+	if *clockName == "stepped" {
+		clock = SteppedClock{Start: exportOptions.StartSeconds, FPS: exportOptions.FPS}
+	} else if *clockName != "wall" {
+		panic(fmt.Sprintf("unknown clock '%s', expected wall or stepped", *clockName))
+	}
 
-		byteRanges, err := spatialIndex.RectangleToIndexedRanges(remappedRectXMin, remappedRectYMin, remappedRectSize, remappedRectSize, 1)
+	frameInterval := time.Duration(float64(time.Second) / exportOptions.FPS)
+	var displays multiDisplay
+	var stats io.Writer = os.Stdout
+	// the HTTP server goes first: it is the step most likely to fail, and a failure must not leave
+	// the terminal in raw mode behind.
+	if *httpAddress != "" {
+		httpDisplay, err := listenHTTPDisplay(*httpAddress, frameInterval)
 		if err != nil {
 			panic(err)
 		}
-		ranges := make([][]int, len(byteRanges))
-		// log.Println("------------")
-		for i, byteRange := range byteRanges {
-			ranges[i] = []int{
-				int(binary.BigEndian.Uint64(byteRange.Start)),
-				int(binary.BigEndian.Uint64(byteRange.End)),
-			}
-			// log.Printf("Start: %x\n", byteRange.Start)
-			// log.Printf("  End: %x\n", byteRange.End)
-			// log.Printf("  Max: %x\n", outputMaxBytes)
+		displays = append(displays, httpDisplay)
+	}
+	if *terminal {
+		terminalDisplay, err := newTerminalDisplay(os.Stdin, os.Stdout, frameInterval)
+		if err != nil {
+			displays.Close()
+			panic(err)
 		}
-		// log.Println("------------")
-
-		// outBytes, _ := json.MarshalIndent(ranges, "", "  ")
-		// log.Println("outBytes: ", string(outBytes))
-
-		rgba := image.NewRGBA(image.Rectangle{Min: image.Point{0, 0}, Max: image.Point{dim, dim}})
-		queriedArea := 0
-		for x := 0; x < dim; x++ {
-			for y := 0; y < dim; y++ {
-
-				onVertical := (x == rectMaxX || x == rectX) && y >= rectY && y <= rectMaxY
-				onHorizontal := (y == rectMaxY || y == rectY) && x >= rectX && x <= rectMaxX
-				if onVertical || onHorizontal {
-					rgba.Set(x, y, color.White)
-					continue
-				}
-
-				remappedX := int(lerp(float64(inputMin), float64(inputMax), float64(x)/float64(dim)))
-				remappedY := int(lerp(float64(inputMin), float64(inputMax), float64(y)/float64(dim)))
-				if y > dim-20 {
-					found := false
-
-					xOnCurveNumberLine := int(lerp(float64(0), float64(curveLength), float64(x)/float64(dim)))
-					for _, curveRange := range ranges {
-						if xOnCurveNumberLine >= curveRange[0] && xOnCurveNumberLine <= curveRange[1] {
-							found = true
-						}
-					}
-
-					if found {
-						rgba.Set(x, y, color.White)
-					} else {
-						rgba.Set(x, y, color.Black)
-					}
-					continue
-				}
-
-				curvePointBytes, err := spatialIndex.GetIndexedPoint(remappedX, remappedY)
-				curvePoint := int(binary.BigEndian.Uint64(curvePointBytes))
-				if err != nil {
-					panic(err)
-				}
-				// if x*2 == y && !logged {
-				// 	log.Printf("[%d,%d]: %d %d", x, y, curvePoint, int((float64(curvePoint)/float64(myCurve.N*myCurve.N))*1000))
-				// }
-
-				curveFloat := (float64(curvePoint) / float64(math.MaxInt64))
-				//sat := (float64(2) + math.Sin(curveFloat*math.Pi*2*saturationFluctuationCount)) * float64(0.3333333)
-				sat := 0.2
-				// if curvePoint >= curvePoints[0] && curvePoint <= curvePoints[len(curvePoints)-1] {
-				// 	sat = 1
-				// }
-				for _, rng := range ranges {
-					if curvePoint >= rng[0] && curvePoint <= rng[1] {
-						sat = 1
-						queriedArea++
-					}
-				}
-				hue := int(curveFloat*rainbowCount*float64(3600)) % 3600
-				rainbow := hsvColor(float64(hue)*0.1, sat, sat)
-				// uvColor := color.RGBA{
-				// 	uint8((float32(x) / float32(width)) * float32(255)),
-				// 	uint8((float32(y) / float32(width)) * float32(255)),
-				// 	255,
-				// 	255,
-				// }
-
-				rgba.Set(x, y, rainbow)
+		displays = append(displays, terminalDisplay)
+		stats = terminalDisplay
+		log.SetOutput(terminalDisplay)
+	} else if *window {
+		displays = append(displays, newGLFWDisplay())
+	}
+	if len(displays) == 0 {
+		panic("nothing to show the demo on, use -http with -window=false, or -terminal")
+	}
+	// without a window there is nothing to close, so an interrupt is how the demo ends
+	displays = append(displays, newSignalDisplay(os.Interrupt, syscall.SIGTERM))
+
+	demo := NewDemo(generator, params, clock, stats)
+	demo.History.KeepAll = exportOptions.CSV != ""
+	if !*terminal {
+		// in the terminal, commands are typed after ':' instead
+		go readCommands(os.Stdin, demo.Commands)
+	}
+
+	if exportOptions.CSV != "" {
+		// deferred, so that the samples are written even when the demo panics
+		defer func() {
+			err := demo.History.WriteCSV(exportOptions.CSV)
+			if err != nil {
+				log.Println(err)
 			}
-		}
+		}()
+	}
 
-		if frames%10 == 0 {
-			fmt.Printf("range count: %d, queriedArea: %d%%\n", len(ranges), int((float64(queriedArea)/float64(rectSize*rectSize))*float64(100)))
-		}
-		frames++
+	err = run_display(displays, demo)
+	if err != nil {
+		panic(err)
+	}
+}
 
-		return rgba
-	})
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, " ")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
 }
 
 func lerp(a, b, lerp float64) float64 {
 	return a*(float64(1)-lerp) + b*lerp
 }
 
+// mix blends b over a, by amount.
+func mix(a, b color.RGBA, amount float64) color.RGBA {
+	return color.RGBA{
+		uint8(lerp(float64(a.R), float64(b.R), amount)),
+		uint8(lerp(float64(a.G), float64(b.G), amount)),
+		uint8(lerp(float64(a.B), float64(b.B), amount)),
+		0xff,
+	}
+}
+
 func hsvColor(H, S, V float64) color.RGBA {
 	Hp := H / 60.0
 	C := V * S
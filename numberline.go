@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// the number line panel along the bottom of the frame shows the curve as a line, zoomed to the
+// span between the first range start and the last range end. A legend strip along its bottom
+// shows the palette colors along the whole curve.
+const numberLineHeight = 36
+
+// layout of the panel, in pixels below its top row
+const (
+	numberLineLabelY    = 2
+	numberLineBarTop    = 9
+	numberLineBarBottom = 21
+	numberLineTickEnd   = 25
+	numberLineTickLabel = 27
+	numberLineLegendTop = 33
+)
+
+var numberLineAxisColor = color.RGBA{0x80, 0x80, 0x80, 0xff}
+var highlightColor = color.RGBA{0xff, 0xd0, 0x00, 0xff}
+
+// numberLine maps curve offsets between start and end onto the width of the panel.
+type numberLine struct {
+	start uint64
+	end   uint64
+	width int
+}
+
+// newNumberLine zooms to the ranges with a little padding on both sides, or to the given
+// fallback span when there are no ranges.
+func newNumberLine(ranges [][]uint64, fallbackStart, fallbackEnd uint64, curveLength uint64, width int) numberLine {
+	start, end := fallbackStart, fallbackEnd
+	if len(ranges) > 0 {
+		start, end = ranges[0][0], ranges[0][1]
+		for _, rng := range ranges {
+			if rng[0] < start {
+				start = rng[0]
+			}
+			if rng[1] > end {
+				end = rng[1]
+			}
+		}
+	}
+
+	padding := (end - start) / 20
+	if padding < 8 {
+		padding = 8
+	}
+	if start > padding {
+		start -= padding
+	} else {
+		start = 0
+	}
+	if curveLength-end > padding {
+		end += padding
+	} else {
+		end = curveLength
+	}
+	return numberLine{start: start, end: end, width: width}
+}
+
+func (line numberLine) toX(offset uint64) float64 {
+	if offset <= line.start {
+		return 0
+	}
+	if offset >= line.end {
+		return float64(line.width)
+	}
+	return (float64(offset-line.start) / float64(line.end-line.start)) * float64(line.width)
+}
+
+func (line numberLine) toOffset(x float64) uint64 {
+	if x <= 0 {
+		return line.start
+	}
+	if x >= float64(line.width) {
+		return line.end
+	}
+	return line.start + uint64((x/float64(line.width))*float64(line.end-line.start))
+}
+
+// bucket is the span of offsets under column x, inclusive.
+func (line numberLine) bucket(x int) (uint64, uint64) {
+	start := line.toOffset(float64(x))
+	end := line.toOffset(float64(x + 1))
+	if end > start && x+1 < line.width {
+		end--
+	}
+	return start, end
+}
+
+// barSpan is the horizontal extent of a range's bar, at least one pixel wide so that tiny
+// ranges don't disappear.
+func (line numberLine) barSpan(rng []uint64) (int, int) {
+	left := int(math.Floor(line.toX(rng[0])))
+	right := int(math.Ceil(line.toX(rng[1])))
+	if right <= left {
+		right = left + 1
+	}
+	return left, right
+}
+
+// rangeAt returns the index of the range whose bar is under x, or the nearest one within a couple
+// of pixels, or -1.
+func (line numberLine) rangeAt(ranges [][]uint64, x int) int {
+	closest := -1
+	closestDistance := 3
+	for i, rng := range ranges {
+		left, right := line.barSpan(rng)
+		distance := 0
+		if x < left {
+			distance = left - x
+		} else if x >= right {
+			distance = x - right + 1
+		}
+		if distance < closestDistance {
+			closest = i
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// draw renders the panel with its top row at top: a bar and a label for each range, and an axis
+// with tick marks. Tick labels are offsets from the origin printed in hex at the left, because
+// at 64 bits the absolute offsets are too long to repeat under every tick.
+//
+// When there are several queries, ranges is their union. The union bars take the upper half of
+// the bar area, and below them each query gets a strip in its own color showing its own ranges.
+//
+// With colorByRange, each bar is drawn in its range's color, as the cells are, and a highlighted
+// range is shown by dimming the other bars.
+func (line numberLine) draw(rgba *image.RGBA, top int, ranges [][]uint64, highlighted int, queries []query, colorByRange bool) {
+	bounds := rgba.Bounds()
+	fill(rgba, image.Rect(0, top, bounds.Max.X, bounds.Max.Y), color.RGBA{0, 0, 0, 0xff})
+	fill(rgba, image.Rect(0, top+numberLineBarBottom, line.width, top+numberLineBarBottom+1), numberLineAxisColor)
+
+	barBottom := numberLineBarBottom
+	if len(queries) > 1 {
+		barBottom = (numberLineBarTop + numberLineBarBottom) / 2
+		stripHeight := (numberLineBarBottom - barBottom - 1) / len(queries)
+		if stripHeight < 1 {
+			stripHeight = 1
+		}
+		for i, q := range queries {
+			stripTop := top + barBottom + 1 + i*stripHeight
+			if stripTop >= top+numberLineBarBottom {
+				break
+			}
+			for _, rng := range q.ranges {
+				left, right := line.barSpan(rng)
+				fill(rgba, image.Rect(left, stripTop, right, stripTop+stripHeight), q.color)
+			}
+		}
+	}
+
+	labelRight := -1
+	for i, rng := range ranges {
+		left, right := line.barSpan(rng)
+		barColor := color.RGBA{0xff, 0xff, 0xff, 0xff}
+		switch {
+		case colorByRange && highlighted >= 0 && i != highlighted:
+			barColor = mix(rangeColor(i), black, 0.6)
+		case colorByRange:
+			barColor = rangeColor(i)
+		case i == highlighted:
+			barColor = highlightColor
+		}
+		fill(rgba, image.Rect(left, top+numberLineBarTop, right, top+barBottom), barColor)
+
+		label := fmt.Sprintf("%d:%s", i, formatCount(rng[1]-rng[0]+1))
+		labelX := (left+right)/2 - textWidth(label, 1)/2
+		if labelX > labelRight+2 || i == highlighted {
+			drawText(rgba, labelX, top+numberLineLabelY, label, barColor, 1)
+			labelRight = labelX + textWidth(label, 1)
+		}
+	}
+
+	step := niceStep((line.end - line.start) / 6)
+	origin := line.start - line.start%step
+	originLabel := fmt.Sprintf("%X", origin)
+	drawText(rgba, 1, top+numberLineTickLabel, originLabel, numberLineAxisColor, 1)
+	labelRight = 1 + textWidth(originLabel, 1)
+
+	for tick := origin; tick <= line.end; tick += step {
+		if tick >= line.start {
+			x := int(math.Round(line.toX(tick)))
+			fill(rgba, image.Rect(x, top+numberLineBarBottom, x+1, top+numberLineTickEnd), numberLineAxisColor)
+
+			label := "+" + formatCount(tick-origin)
+			labelX := x - textWidth(label, 1)/2
+			if tick != origin && labelX > labelRight+3 {
+				drawText(rgba, labelX, top+numberLineTickLabel, label, numberLineAxisColor, 1)
+				labelRight = labelX + textWidth(label, 1)
+			}
+		}
+		if tick+step < tick {
+			break
+		}
+	}
+}
+
+// drawMarker draws a tick across the bars at offset, like a cursor on the number line.
+func (line numberLine) drawMarker(rgba *image.RGBA, top int, offset uint64, markerColor color.RGBA) {
+	x := int(line.toX(offset))
+	if x >= line.width {
+		x = line.width - 1
+	}
+	fill(rgba, image.Rect(x, top+numberLineBarTop-2, x+1, top+numberLineTickEnd), markerColor)
+}
+
+// drawLegend fills the bottom rows of the panel with the palette colors of the whole curve, from
+// its start at the left to its end at the right, and marks the part of it the number line above
+// is zoomed to.
+func (line numberLine) drawLegend(rgba *image.RGBA, top int, curveLength uint64, colorAt func(offset uint64) color.RGBA) {
+	whole := numberLine{start: 0, end: curveLength, width: line.width}
+	for x := 0; x < line.width; x++ {
+		fill(rgba, image.Rect(x, top+numberLineLegendTop, x+1, top+numberLineHeight), colorAt(whole.toOffset(float64(x)+0.5)))
+	}
+	left, right := whole.barSpan([]uint64{line.start, line.end})
+	fill(rgba, image.Rect(left, top+numberLineLegendTop-1, right, top+numberLineLegendTop), numberLineAxisColor)
+}
+
+// niceStep rounds up to the next 1, 2 or 5 times a power of ten.
+func niceStep(raw uint64) uint64 {
+	step := uint64(1)
+	for {
+		for _, multiple := range []uint64{1, 2, 5, 10} {
+			if step > math.MaxUint64/multiple {
+				return math.MaxUint64
+			}
+			if step*multiple >= raw {
+				return step * multiple
+			}
+		}
+		step *= 10
+	}
+}
+
+// formatCount prints a count with an SI suffix and about three significant digits, like 950, 12K or 4.3E.
+func formatCount(count uint64) string {
+	if count < 1000 {
+		return fmt.Sprintf("%d", count)
+	}
+	value := float64(count)
+	for _, suffix := range []string{"K", "M", "G", "T", "P", "E"} {
+		value /= 1000
+		if value < 1000 || suffix == "E" {
+			if value < 10 && math.Round(value*10) != math.Round(value)*10 {
+				return fmt.Sprintf("%.1f%s", value, suffix)
+			}
+			return fmt.Sprintf("%.0f%s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+func fill(rgba *image.RGBA, rect image.Rectangle, fillColor color.RGBA) {
+	rect = rect.Intersect(rgba.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			rgba.SetRGBA(x, y, fillColor)
+		}
+	}
+}
@@ -34,36 +34,108 @@ const (
 	` + "\x00"
 )
 
+// the first row of the texture is the top row of the image, so V runs from 0 at the top of the
+// window to 1 at the bottom. That keeps the window the same way up as exported frames.
 var fullscreenQuad = []float32{
 	//  X, Y, Z, U, V
-	-1, 1, 0, 0, 1,
-	-1, -1, 0, 0, 0,
-	1, -1, 0, 1, 0,
-	-1, 1, 0, 0, 1,
-	1, 1, 0, 1, 1,
-	1, -1, 0, 1, 0,
+	-1, 1, 0, 0, 0,
+	-1, -1, 0, 0, 1,
+	1, -1, 0, 1, 1,
+	-1, 1, 0, 0, 0,
+	1, 1, 0, 1, 0,
+	1, -1, 0, 1, 1,
+}
+
+// glfwDisplay is the Display backed by an OpenGL window. The fullscreen quad is uploaded once,
+// and frames are streamed into one texture that is only reallocated when the frame size changes.
+type glfwDisplay struct {
+	window      *glfw.Window
+	program     uint32
+	vao         uint32
+	vbo         uint32
+	texture     uint32
+	textureSize image.Point
+	resources   DisplayResources
+	input       []InputEvent
 }
 
 // basic OpenGL based display application copy and pasted from
 // https://kylewbanks.com/blog/tutorial-opengl-with-golang-part-1-hello-opengl
-func run_opengl_app(getImage func() *image.RGBA) {
+//
+// GLFW has to be driven from the main thread, so newGLFWDisplay and every method of the display
+// must be called from the main goroutine.
+func newGLFWDisplay() *glfwDisplay {
 	runtime.LockOSThread()
 
-	window := initGlfw()
-	defer glfw.Terminate()
-
-	program := initOpenGL()
+	display := &glfwDisplay{window: initGlfw()}
+	initInputCallbacks(display.window, func(event InputEvent) {
+		display.input = append(display.input, event)
+	})
+	display.program = initOpenGL()
+	display.vao, display.vbo = makeVertexArrayObject(fullscreenQuad, display.program)
+	display.resources.VertexArrays++
+	display.resources.Buffers++
+	return display
+}
 
-	for !window.ShouldClose() {
-		texture, err := newTexture(getImage())
+func (display *glfwDisplay) Present(rgba *image.RGBA) error {
+	if display.texture == 0 || display.textureSize != rgba.Rect.Size() {
+		display.deleteTexture()
+		texture, err := newTexture(rgba)
+		if err != nil {
+			return err
+		}
+		display.texture = texture
+		display.textureSize = rgba.Rect.Size()
+		display.resources.Textures++
+		display.resources.TextureAllocations++
+	} else {
+		err := updateTexture(display.texture, rgba)
 		if err != nil {
-			panic(err)
+			return err
 		}
+	}
+
+	draw(display.vao, display.texture, display.window, display.program)
+	return nil
+}
+
+func (display *glfwDisplay) Resources() DisplayResources {
+	return display.resources
+}
 
-		draw(makeVertexArrayObject(fullscreenQuad, program), texture, window, program)
+func (display *glfwDisplay) deleteTexture() {
+	if display.texture != 0 {
+		gl.DeleteTextures(1, &display.texture)
+		display.texture = 0
+		display.resources.Textures--
 	}
 }
 
+// PollInput runs the GLFW event loop, which calls the input callbacks, and hands over what they queued.
+func (display *glfwDisplay) PollInput() []InputEvent {
+	glfw.PollEvents()
+	input := display.input
+	display.input = nil
+	return input
+}
+
+func (display *glfwDisplay) ShouldClose() bool {
+	return display.window.ShouldClose()
+}
+
+func (display *glfwDisplay) Close() error {
+	display.deleteTexture()
+	gl.DeleteVertexArrays(1, &display.vao)
+	gl.DeleteBuffers(1, &display.vbo)
+	gl.DeleteProgram(display.program)
+	display.resources.VertexArrays--
+	display.resources.Buffers--
+	display.resources.TextureAllocations = 0
+	glfw.Terminate()
+	return nil
+}
+
 func draw(vertexArrayObject uint32, texture uint32, window *glfw.Window, program uint32) {
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 	gl.UseProgram(program)
@@ -75,7 +147,6 @@ func draw(vertexArrayObject uint32, texture uint32, window *glfw.Window, program
 
 	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(fullscreenQuad)/3))
 
-	glfw.PollEvents()
 	window.SwapBuffers()
 }
 
@@ -123,8 +194,50 @@ func initGlfw() *glfw.Window {
 	return window
 }
 
+// initInputCallbacks translates GLFW mouse and keyboard input into InputEvents. The callbacks run
+// inside glfw.PollEvents, on the same goroutine as the frame loop.
+func initInputCallbacks(window *glfw.Window, onInput func(InputEvent)) {
+	// the window is the same size as the frame and the image is drawn the same way up,
+	// so cursor positions already are frame pixels.
+	onPointer := func(event PointerEvent) {
+		onInput(InputEvent{Kind: InputPointer, Pointer: event})
+	}
+
+	window.SetCursorPosCallback(func(w *glfw.Window, xpos float64, ypos float64) {
+		onPointer(PointerEvent{Kind: PointerMove, X: xpos, Y: ypos, Radius: cornerGrabDistance})
+	})
+
+	window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		event := PointerEvent{Kind: PointerDown, Radius: cornerGrabDistance}
+		if action == glfw.Release {
+			event.Kind = PointerUp
+		}
+		switch button {
+		case glfw.MouseButtonLeft:
+			event.Button = PrimaryButton
+		case glfw.MouseButtonRight:
+			event.Button = SecondaryButton
+		default:
+			return
+		}
+		event.X, event.Y = w.GetCursorPos()
+		onPointer(event)
+	})
+
+	window.SetScrollCallback(func(w *glfw.Window, xoff float64, yoff float64) {
+		event := PointerEvent{Kind: PointerScroll, Scroll: yoff}
+		event.X, event.Y = w.GetCursorPos()
+		onPointer(event)
+	})
+
+	window.SetCharCallback(func(w *glfw.Window, char rune) {
+		onInput(InputEvent{Kind: InputChar, Char: char})
+	})
+}
+
 // texure coordinate stuff sourced from https://github.com/go-gl/example/blob/master/gl41core-cube/cube.go
-func makeVertexArrayObject(points []float32, program uint32) uint32 {
+// it returns the vertex buffer too, so that it can be deleted along with the vertex array.
+func makeVertexArrayObject(points []float32, program uint32) (uint32, uint32) {
 
 	var vao uint32
 	gl.GenVertexArrays(1, &vao)
@@ -143,7 +256,7 @@ func makeVertexArrayObject(points []float32, program uint32) uint32 {
 	gl.EnableVertexAttribArray(texCoordAttrib)
 	gl.VertexAttribPointerWithOffset(texCoordAttrib, 2, gl.FLOAT, false, 5*4, 3*4)
 
-	return vao
+	return vao, vbo
 }
 
 func compileShader(source string, shaderType uint32) (uint32, error) {
@@ -196,3 +309,26 @@ func newTexture(rgba *image.RGBA) (uint32, error) {
 
 	return texture, nil
 }
+
+// updateTexture overwrites the pixels of a texture that newTexture allocated for a frame of the same size.
+func updateTexture(texture uint32, rgba *image.RGBA) error {
+
+	if rgba.Stride != rgba.Rect.Size().X*4 {
+		return fmt.Errorf("unsupported stride")
+	}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexSubImage2D(
+		gl.TEXTURE_2D,
+		0,
+		0,
+		0,
+		int32(rgba.Rect.Size().X),
+		int32(rgba.Rect.Size().Y),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(rgba.Pix))
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// Palette colors the cells of the curve by their position along it.
+type Palette interface {
+	Name() string
+
+	// Color returns the color at t, where every whole number of t is one trip through the
+	// palette: the curve is colored FrameParams.PaletteCycles times over. Cells that no range
+	// reads are drawn dim.
+	Color(t float64, dim bool) color.RGBA
+}
+
+// rainbowPalette is the original coloring: hues all the way around, and dim cells barely
+// saturated as well as dark.
+type rainbowPalette struct{}
+
+func (rainbowPalette) Name() string {
+	return "rainbow"
+}
+
+func (rainbowPalette) Color(t float64, dim bool) color.RGBA {
+	sat := 1.0
+	if dim {
+		sat = 0.2
+	}
+	hue := int(t*float64(3600)) % 3600
+	return hsvColor(float64(hue)*0.1, sat, sat)
+}
+
+// gradientPalette interpolates between evenly spaced stops. It jumps back from the last stop to
+// the first at every whole number of t, unless they are the same color, as in a cyclic palette.
+type gradientPalette struct {
+	name  string
+	stops []color.RGBA
+}
+
+func (palette gradientPalette) Name() string {
+	return palette.name
+}
+
+func (palette gradientPalette) Color(t float64, dim bool) color.RGBA {
+	position := (t - math.Floor(t)) * float64(len(palette.stops)-1)
+	// a t that is not finite ends up NaN here, so the index is kept in range on both sides
+	index := int(position)
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(palette.stops)-1 {
+		index = len(palette.stops) - 2
+	}
+	result := mix(palette.stops[index], palette.stops[index+1], position-float64(index))
+	if dim {
+		return dimColor(result)
+	}
+	return result
+}
+
+// dimColor fades a color most of the way to gray and darkens it, like the rainbow palette's dim
+// cells, so that whatever the ranges read stands out in any palette.
+func dimColor(c color.RGBA) color.RGBA {
+	gray := uint8((int(c.R)*299 + int(c.G)*587 + int(c.B)*114) / 1000)
+	desaturated := mix(color.RGBA{gray, gray, gray, 0xff}, c, 0.2)
+	return mix(black, desaturated, 0.25)
+}
+
+// palettes are the built in palettes by name. viridis and cividis are readable with the common
+// kinds of color blindness, and twilight is cyclic, so it has no seam where it repeats.
+var palettes = map[string]Palette{
+	"rainbow": rainbowPalette{},
+	"viridis": gradientPalette{name: "viridis", stops: []color.RGBA{
+		{0x44, 0x01, 0x54, 0xff},
+		{0x47, 0x2c, 0x7a, 0xff},
+		{0x3b, 0x51, 0x8b, 0xff},
+		{0x2c, 0x71, 0x8e, 0xff},
+		{0x21, 0x90, 0x8d, 0xff},
+		{0x27, 0xad, 0x81, 0xff},
+		{0x5c, 0xc8, 0x63, 0xff},
+		{0xaa, 0xdc, 0x32, 0xff},
+		{0xfd, 0xe7, 0x25, 0xff},
+	}},
+	"cividis": gradientPalette{name: "cividis", stops: []color.RGBA{
+		{0x00, 0x22, 0x4e, 0xff},
+		{0x12, 0x35, 0x70, 0xff},
+		{0x3b, 0x49, 0x6c, 0xff},
+		{0x57, 0x5d, 0x6d, 0xff},
+		{0x70, 0x71, 0x73, 0xff},
+		{0x8a, 0x86, 0x78, 0xff},
+		{0xa5, 0x9c, 0x74, 0xff},
+		{0xc3, 0xb3, 0x69, 0xff},
+		{0xfe, 0xe8, 0x38, 0xff},
+	}},
+	"gray": gradientPalette{name: "gray", stops: []color.RGBA{
+		{0x20, 0x20, 0x20, 0xff},
+		{0xf0, 0xf0, 0xf0, 0xff},
+	}},
+	"twilight": gradientPalette{name: "twilight", stops: []color.RGBA{
+		{0xe2, 0xd9, 0xe2, 0xff},
+		{0x9e, 0xbb, 0xc9, 0xff},
+		{0x6a, 0x8f, 0xc1, 0xff},
+		{0x5c, 0x4f, 0x9e, 0xff},
+		{0x2f, 0x14, 0x36, 0xff},
+		{0x7a, 0x2e, 0x56, 0xff},
+		{0xb9, 0x5d, 0x50, 0xff},
+		{0xd4, 0xa8, 0x8a, 0xff},
+		{0xe2, 0xd9, 0xe2, 0xff},
+	}},
+}
+
+// paletteNames lists the built in palettes in the order "palette next" steps through them.
+var paletteNames = []string{"rainbow", "viridis", "cividis", "gray", "twilight"}
+
+// nextPalette returns the palette after current in paletteNames, wrapping around.
+func nextPalette(current Palette) Palette {
+	index := -1
+	for i, name := range paletteNames {
+		if name == current.Name() {
+			index = i
+		}
+	}
+	return palettes[paletteNames[(index+1)%len(paletteNames)]]
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPalettesColorAnyPosition(t *testing.T) {
+	for _, name := range paletteNames {
+		palette := palettes[name]
+		for _, position := range []float64{0, 0.5, 1, 7.25, -3.5, math.Inf(1), math.Inf(-1), math.NaN()} {
+			for _, dim := range []bool{true, false} {
+				// only checks that no position panics
+				palette.Color(position, dim)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// forEachBand splits the rows [0, height) into one band per worker and calls render for every
+// band on its own goroutine, then waits for all of them. Bands never share rows, so render may
+// write to its rows of an image without locking.
+func forEachBand(height, workers int, render func(band, minY, maxY int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > height {
+		workers = height
+	}
+	if workers <= 1 {
+		render(0, 0, height)
+		return
+	}
+
+	var waitGroup sync.WaitGroup
+	for band := 0; band < workers; band++ {
+		minY := (height * band) / workers
+		maxY := (height * (band + 1)) / workers
+		waitGroup.Add(1)
+		go func(band, minY, maxY int) {
+			defer waitGroup.Done()
+			render(band, minY, maxY)
+		}(band, minY, maxY)
+	}
+	waitGroup.Wait()
+}
+
+// setPixel writes straight into rgba.Pix, which is a lot cheaper than rgba.Set with its
+// color model conversion and bounds check.
+func setPixel(rgba *image.RGBA, x, y int, pixel color.RGBA) {
+	offset := (y-rgba.Rect.Min.Y)*rgba.Stride + (x-rgba.Rect.Min.X)*4
+	pix := rgba.Pix[offset : offset+4 : offset+4]
+	pix[0] = pixel.R
+	pix[1] = pixel.G
+	pix[2] = pixel.B
+	pix[3] = pixel.A
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+// Sample is what the plot tracks about one frame.
+type Sample struct {
+	Frame        int
+	Seconds      float64
+	RangeCount   int
+	Oversampling float64
+
+	// RectArea is the area of the rectangle, or the bounding box of all of them, in frame pixels.
+	RectArea float64
+}
+
+// History records a Sample for every frame, for the plot and for writing them all out as CSV.
+// Unless KeepAll is set, it only keeps the last PlotSeconds the plot can show, so a demo that runs
+// for days doesn't grow without bound.
+type History struct {
+	Samples []Sample
+	KeepAll bool
+}
+
+func (history *History) Add(frame int, seconds float64, params FrameParams, stats FrameStats) {
+	pixels := stats.Rect.ToPixelRect(params.Size)
+	history.Samples = append(history.Samples, Sample{
+		Frame:        frame,
+		Seconds:      seconds,
+		RangeCount:   stats.RangeCount,
+		Oversampling: stats.Oversampling(),
+		RectArea:     pixels.Width * pixels.Height,
+	})
+	if !history.KeepAll {
+		history.Samples = history.Last(params.PlotSeconds)
+	}
+}
+
+// Last returns the samples of the last window seconds of animation time.
+func (history *History) Last(window float64) []Sample {
+	if len(history.Samples) == 0 {
+		return nil
+	}
+	since := history.Samples[len(history.Samples)-1].Seconds - window
+	first := len(history.Samples) - 1
+	for first > 0 && history.Samples[first-1].Seconds >= since {
+		first--
+	}
+	return history.Samples[first:]
+}
+
+// WriteCSV writes every sample, one row per frame, under a header row.
+func (history *History) WriteCSV(output string) error {
+	file, err := createOutput(output)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"frame", "seconds", "range_count", "oversampling", "rect_area"})
+	for _, sample := range history.Samples {
+		writer.Write([]string{
+			strconv.Itoa(sample.Frame),
+			formatFloat(sample.Seconds),
+			strconv.Itoa(sample.RangeCount),
+			formatFloat(sample.Oversampling),
+			formatFloat(sample.RectArea),
+		})
+	}
+	writer.Flush()
+	err = writer.Error()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// the plot sits right above the number line, and shows this many seconds unless told otherwise
+const plotHeight = 64
+const defaultPlotSeconds = 10
+
+var plotRangeCountColor = color.RGBA{0x00, 0xe5, 0xff, 0xff}
+var plotOversamplingColor = color.RGBA{0xff, 0x6e, 0x40, 0xff}
+var plotRectAreaColor = color.RGBA{0x76, 0xff, 0x03, 0xff}
+
+// plotSeries is one line of the plot.
+type plotSeries struct {
+	label string
+	color color.RGBA
+	value func(Sample) float64
+	text  func(Sample) string
+}
+
+var plotSeriesList = []plotSeries{
+	{
+		label: "RANGES",
+		color: plotRangeCountColor,
+		value: func(sample Sample) float64 { return float64(sample.RangeCount) },
+		text:  func(sample Sample) string { return strconv.Itoa(sample.RangeCount) },
+	},
+	{
+		label: "OVERSAMPLING",
+		color: plotOversamplingColor,
+		value: func(sample Sample) float64 { return sample.Oversampling },
+		text:  func(sample Sample) string { return fmt.Sprintf("%.2f", sample.Oversampling) },
+	},
+	{
+		label: "AREA",
+		color: plotRectAreaColor,
+		value: func(sample Sample) float64 { return sample.RectArea },
+		text:  func(sample Sample) string { return fmt.Sprintf("%.0f", sample.RectArea) },
+	},
+}
+
+// drawPlot draws the samples as a chart over the bottom of the main view, time from left to right
+// across window seconds. Each series is scaled to its own maximum, so the chart shows when they
+// rise and fall together rather than how they compare, and the latest values are written above it.
+func drawPlot(rgba *image.RGBA, samples []Sample, window float64) {
+	size := rgba.Rect.Dx()
+	bottom := rgba.Rect.Dy() - numberLineHeight
+	area := image.Rect(0, bottom-plotHeight, size, bottom)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			rgba.SetRGBA(x, y, mix(rgba.RGBAAt(x, y), black, 0.7))
+		}
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	last := samples[len(samples)-1]
+	chart := image.Rect(area.Min.X+hudMargin, area.Min.Y+hudMargin+hudLineHeight, area.Max.X-hudMargin, area.Max.Y-hudMargin)
+	textX := area.Min.X + hudMargin
+	for _, series := range plotSeriesList {
+		text := fmt.Sprintf("%s %s", series.label, series.text(last))
+		drawText(rgba, textX, area.Min.Y+hudMargin, text, series.color, 1)
+		textX += textWidth(text, 1) + glyphAdvance*2
+
+		maximum := 0.0
+		for _, sample := range samples {
+			if value := series.value(sample); value > maximum {
+				maximum = value
+			}
+		}
+		toScreen := func(sample Sample) (float64, float64) {
+			x := float64(chart.Max.X-1) - (last.Seconds-sample.Seconds)/window*float64(chart.Dx()-1)
+			y := float64(chart.Max.Y - 1)
+			if maximum > 0 {
+				y -= series.value(sample) / maximum * float64(chart.Dy()-1)
+			}
+			return x, y
+		}
+		for i := 0; i+1 < len(samples); i++ {
+			x0, y0 := toScreen(samples[i])
+			x1, y1 := toScreen(samples[i+1])
+			drawLine(rgba, chart, x0, y0, x1, y1, series.color)
+		}
+	}
+}
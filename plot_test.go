@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHistoryKeepsOnlyWhatThePlotShows(t *testing.T) {
+	params := testParams()
+	params.PlotSeconds = 2
+	trimmed := History{}
+	all := History{KeepAll: true}
+	for frame := 0; frame < 300; frame++ {
+		seconds := float64(frame) / 30
+		trimmed.Add(frame, seconds, params, FrameStats{})
+		all.Add(frame, seconds, params, FrameStats{})
+	}
+
+	if len(all.Samples) != 300 {
+		t.Errorf("KeepAll should keep every sample, kept %d", len(all.Samples))
+	}
+	// 2 seconds at 30 fps, counting both ends
+	if len(trimmed.Samples) != 61 {
+		t.Errorf("expected the last 61 samples, kept %d", len(trimmed.Samples))
+	}
+	if first := trimmed.Samples[0]; first.Frame != 239 {
+		t.Errorf("expected the samples to start at frame 239, got %d", first.Frame)
+	}
+}
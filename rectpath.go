@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// RectPath decides where the query rectangle is at a given animation time. Paths are written in
+// pixels of the unzoomed frame, size pixels wide, so they stay put when the viewport changes.
+type RectPath interface {
+	RectAt(seconds float64, size int) QueryRect
+}
+
+// LissajousPath is the original animation: sin/cos motion with a pulsing size.
+type LissajousPath struct{}
+
+func (LissajousPath) RectAt(seconds float64, size int) QueryRect {
+	rectX := int(float64(size) * (float64(0.4) + math.Sin(seconds*float64(1.3))*float64(0.3)))
+	rectY := int(float64(size) * (float64(0.5) + math.Cos(seconds*float64(0.3))*float64(0.2)))
+	rectSize := 1 + int(float64(25)*(float64(1)+math.Sin(seconds*float64(0.843))))
+	return PixelRect{float64(rectX), float64(rectY), float64(rectSize), float64(rectSize)}.ToQueryRect(size)
+}
+
+// AspectSweepPath drifts slowly around the middle of the frame while sweeping the aspect ratio
+// of the rectangle from a tall thin strip (1:MaxAspect) to a wide box (MaxAspect:1) and back,
+// keeping the area roughly constant. Long thin queries behave very differently on a Hilbert
+// curve than squares do.
+type AspectSweepPath struct {
+	MaxAspect float64
+	Area      float64
+}
+
+func (path AspectSweepPath) RectAt(seconds float64, size int) QueryRect {
+	aspect := math.Pow(path.MaxAspect, math.Sin(seconds*float64(0.5)))
+	width := math.Max(1, math.Round(math.Sqrt(path.Area*aspect)))
+	height := math.Max(1, math.Round(path.Area/width))
+
+	centerX := float64(size) * (float64(0.5) + math.Sin(seconds*float64(0.21))*float64(0.1))
+	centerY := float64(size) * (float64(0.5) + math.Cos(seconds*float64(0.17))*float64(0.1))
+	rectX := math.Floor(centerX - width/2)
+	rectY := math.Floor(centerY - height/2)
+	return PixelRect{rectX, rectY, width, height}.ToQueryRect(size)
+}
+
+// FixedPath always returns the same rectangle.
+type FixedPath struct {
+	Rect PixelRect
+}
+
+func (path FixedPath) RectAt(seconds float64, size int) QueryRect {
+	return path.Rect.ToQueryRect(size)
+}
+
+// placedPath is a rectangle that was already placed for a frame. Paths place their rectangles in
+// pixels, so when a frame is drawn as smaller panels, they are placed once for the whole frame.
+type placedPath struct {
+	rect QueryRect
+}
+
+func (path placedPath) RectAt(seconds float64, size int) QueryRect {
+	return path.rect
+}
+
+// Keyframe places the rectangle at a given time. Between keyframes the rectangle is linearly
+// interpolated.
+type Keyframe struct {
+	Seconds float64 `json:"seconds"`
+	PixelRect
+}
+
+// KeyframePath is loaded from a JSON file like
+//
+//	{ "loop": true, "keyframes": [ { "seconds": 0, "x": 100, "y": 100, "width": 20, "height": 20 }, ... ] }
+//
+// Before the first keyframe the rectangle holds still. After the last one it either holds still
+// or, with loop set, starts over from the first keyframe.
+type KeyframePath struct {
+	Loop      bool       `json:"loop"`
+	Keyframes []Keyframe `json:"keyframes"`
+}
+
+func LoadKeyframePath(filename string) (*KeyframePath, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	path := &KeyframePath{}
+	err = json.Unmarshal(bytes, path)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse keyframes file %s: %s", filename, err)
+	}
+	if len(path.Keyframes) == 0 {
+		return nil, fmt.Errorf("keyframes file %s has no keyframes", filename)
+	}
+	for i, keyframe := range path.Keyframes {
+		if keyframe.Width <= 0 || keyframe.Height <= 0 {
+			return nil, fmt.Errorf("keyframes file %s: keyframe %d is %gx%g, width and height must be positive", filename, i, keyframe.Width, keyframe.Height)
+		}
+	}
+	for i := 1; i < len(path.Keyframes); i++ {
+		if path.Keyframes[i].Seconds <= path.Keyframes[i-1].Seconds {
+			return nil, fmt.Errorf("keyframes file %s: keyframe %d is not later than keyframe %d", filename, i, i-1)
+		}
+	}
+	return path, nil
+}
+
+func (path *KeyframePath) RectAt(seconds float64, size int) QueryRect {
+	first := path.Keyframes[0]
+	last := path.Keyframes[len(path.Keyframes)-1]
+	duration := last.Seconds - first.Seconds
+	if path.Loop && duration > 0 {
+		seconds = first.Seconds + math.Mod(seconds-first.Seconds, duration)
+		if seconds < first.Seconds {
+			seconds += duration
+		}
+	}
+
+	if seconds <= first.Seconds {
+		return first.ToQueryRect(size)
+	}
+	for i := 1; i < len(path.Keyframes); i++ {
+		to := path.Keyframes[i]
+		if seconds < to.Seconds {
+			from := path.Keyframes[i-1]
+			t := (seconds - from.Seconds) / (to.Seconds - from.Seconds)
+			return PixelRect{
+				X:      lerp(from.X, to.X, t),
+				Y:      lerp(from.Y, to.Y, t),
+				Width:  lerp(from.Width, to.Width, t),
+				Height: lerp(from.Height, to.Height, t),
+			}.ToQueryRect(size)
+		}
+	}
+	return last.ToQueryRect(size)
+}
+
+// parseRect parses "x,y,width,height" in pixels of the unzoomed frame.
+func parseRect(value string) (PixelRect, error) {
+	numbers, err := parseFloats(value, 4, "x,y,width,height")
+	if err != nil {
+		return PixelRect{}, err
+	}
+	return PixelRect{numbers[0], numbers[1], numbers[2], numbers[3]}, nil
+}
+
+// newRectPath builds the RectPath selected on the command line.
+func newRectPath(name, rect, keyframesFile string) (RectPath, error) {
+	switch name {
+	case "lissajous":
+		return LissajousPath{}, nil
+	case "aspect":
+		return AspectSweepPath{MaxAspect: 16, Area: 40 * 40}, nil
+	case "fixed":
+		parsed, err := parseRect(rect)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Width <= 0 || parsed.Height <= 0 {
+			return nil, fmt.Errorf("-rect %s: width and height must be positive", rect)
+		}
+		return FixedPath{Rect: parsed}, nil
+	case "keyframes":
+		if keyframesFile == "" {
+			return nil, fmt.Errorf("-path keyframes requires -keyframes <file.json>")
+		}
+		return LoadKeyframePath(keyframesFile)
+	}
+	return nil, fmt.Errorf("unknown rectangle path '%s', expected lissajous, aspect, fixed or keyframes", name)
+}
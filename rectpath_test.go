@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyframePath(t *testing.T) {
+	keyframes := []Keyframe{
+		{Seconds: 1, PixelRect: PixelRect{0, 0, 10, 10}},
+		{Seconds: 3, PixelRect: PixelRect{20, 0, 10, 30}},
+		{Seconds: 5, PixelRect: PixelRect{20, 40, 10, 10}},
+	}
+	cases := []struct {
+		loop    bool
+		seconds float64
+		rect    PixelRect
+	}{
+		{false, 0, PixelRect{0, 0, 10, 10}},
+		{false, 1, PixelRect{0, 0, 10, 10}},
+		{false, 2, PixelRect{10, 0, 10, 20}},
+		{false, 3, PixelRect{20, 0, 10, 30}},
+		{false, 4, PixelRect{20, 20, 10, 20}},
+		{false, 5, PixelRect{20, 40, 10, 10}},
+		{false, 60, PixelRect{20, 40, 10, 10}},
+		{true, 2, PixelRect{10, 0, 10, 20}},
+		{true, 5, PixelRect{0, 0, 10, 10}},
+		{true, 6, PixelRect{10, 0, 10, 20}},
+		{true, 12, PixelRect{20, 20, 10, 20}},
+		{true, 0, PixelRect{20, 20, 10, 20}},
+		{true, -3, PixelRect{0, 0, 10, 10}},
+	}
+	for _, c := range cases {
+		path := &KeyframePath{Loop: c.loop, Keyframes: keyframes}
+		rect := path.RectAt(c.seconds, 100)
+		if expected := c.rect.ToQueryRect(100); !closeRect(rect, expected) {
+			t.Errorf("loop %t at %gs: expected %+v, got %+v", c.loop, c.seconds, expected, rect)
+		}
+	}
+}
+
+func TestLoadKeyframePath(t *testing.T) {
+	files := []struct {
+		contents string
+		valid    bool
+	}{
+		{`{"loop": true, "keyframes": [{"seconds": 0, "x": 1, "y": 2, "width": 3, "height": 4}, {"seconds": 1, "x": 5, "y": 6, "width": 7, "height": 8}]}`, true},
+		{`{"keyframes": []}`, false},
+		{`{"keyframes": [{"seconds": 0, "x": 1, "y": 1, "width": 3, "height": 4}, {"seconds": 0, "x": 1, "y": 1, "width": 3, "height": 4}]}`, false},
+		{`{"keyframes": [{"seconds": 0, "x": 100, "y": 100, "width": -20, "height": 10}]}`, false},
+		{`{"keyframes": [{"seconds": 0, "x": 100, "y": 100, "width": 20, "height": 0}]}`, false},
+		{`not json`, false},
+	}
+	directory := t.TempDir()
+	for _, file := range files {
+		filename := filepath.Join(directory, "keyframes.json")
+		err := ioutil.WriteFile(filename, []byte(file.contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = LoadKeyframePath(filename)
+		if file.valid && err != nil {
+			t.Errorf("%s: %s", file.contents, err)
+		}
+		if !file.valid && err == nil {
+			t.Errorf("%s: expected an error", file.contents)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	imagedraw "image/draw"
+	"math"
+)
+
+// splitPanels is where the two panels of a split frame go: side by side, half the frame wide and
+// as tall as they are wide, in the middle of the frame.
+func splitPanels(size int) [2]image.Rectangle {
+	half := size / 2
+	top := (size - half) / 2
+	return [2]image.Rectangle{
+		image.Rect(0, top, half, top+half),
+		image.Rect(half, top, half*2, top+half),
+	}
+}
+
+// compareGenerator is the generator for the right panel of a split frame.
+func (generator *FrameGenerator) compareGenerator() *FrameGenerator {
+	if generator.compare == nil {
+		generator.compare = NewFrameGenerator()
+	}
+	generator.compare.Workers = generator.Workers
+	return generator.compare
+}
+
+// renderSplit renders the rectangles on Curve in the left panel and on CompareCurve in the right
+// one, and labels each with its range count and oversampling.
+func (generator *FrameGenerator) renderSplit(seconds float64, params FrameParams) (*image.RGBA, FrameStats, error) {
+	size := params.Size
+	rgba := image.NewRGBA(image.Rect(0, 0, size, size))
+	imagedraw.Draw(rgba, rgba.Rect, &image.Uniform{black}, image.Point{}, imagedraw.Src)
+
+	placed := params.placed(seconds)
+	var stats FrameStats
+	for i, panel := range splitPanels(size) {
+		panelParams := placed
+		panelParams.Size = panel.Dx()
+		panelParams.CompareCurve = ""
+		panelParams.HUD = false
+		panelParams.Pointer = image.Point{-1, -1}
+		if params.Pointer.In(panel) {
+			panelParams.Pointer = params.Pointer.Sub(panel.Min)
+		}
+		panelGenerator := generator
+		if i == 1 {
+			panelParams.Curve = params.CompareCurve
+			panelGenerator = generator.compareGenerator()
+		}
+
+		panelRGBA, panelStats, err := panelGenerator.render(seconds, panelParams)
+		if err != nil {
+			return nil, FrameStats{}, err
+		}
+		imagedraw.Draw(rgba, panel, panelRGBA, image.Point{}, imagedraw.Src)
+
+		labelY := panel.Min.Y - hudMargin - hudLineHeight - glyphHeight
+		drawText(rgba, panel.Min.X+hudMargin, labelY, panelParams.Curve, white, 1)
+		drawText(
+			rgba, panel.Min.X+hudMargin, labelY+hudLineHeight,
+			fmt.Sprintf("%d RANGES  OVERSAMPLING %.2f", panelStats.RangeCount, panelStats.Oversampling()), white, 1,
+		)
+
+		if i == 0 {
+			stats = panelStats
+		} else {
+			stats.Compare = &panelStats
+		}
+	}
+
+	if params.HUD {
+		drawHUD(rgba, []string{
+			fmt.Sprintf("FPS %.1f", params.FPS),
+			fmt.Sprintf("BITS %d  COST %g", params.CurveBits, params.IOPSCostParam),
+		})
+	}
+	return rgba, stats, nil
+}
+
+// handleSplitPointer routes a pointer event on a split frame to the panel it is over, as if that
+// panel were the whole frame. Both panels show the same rectangles, so either one edits them.
+func handleSplitPointer(generator *FrameGenerator, params *FrameParams, editor *RectEditor, event PointerEvent) {
+	point := image.Point{int(math.Floor(event.X)), int(math.Floor(event.Y))}
+	if event.Kind == PointerMove {
+		params.Pointer = point
+	}
+
+	panels := splitPanels(params.Size)
+	index := 0
+	if point.X >= panels[1].Min.X {
+		index = 1
+	}
+	panel := panels[index]
+	// a drag may leave the panel, but a click has to land inside it
+	if event.Kind == PointerDown && !point.In(panel) {
+		return
+	}
+
+	panelParams := *params
+	panelParams.Size = panel.Dx()
+	panelParams.CompareCurve = ""
+	panelGenerator := generator
+	if index == 1 {
+		panelGenerator = generator.compareGenerator()
+	}
+	event.X -= float64(panel.Min.X)
+	event.Y -= float64(panel.Min.Y)
+	handleScreenPointer(panelGenerator, &panelParams, editor, event)
+
+	panelParams.Size = params.Size
+	panelParams.CompareCurve = params.CompareCurve
+	panelParams.Pointer = params.Pointer
+	*params = panelParams
+}
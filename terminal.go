@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// terminalDisplay draws frames into a terminal with 24 bit colors, for quick checks over ssh.
+// Each character cell shows two pixels: the upper half block takes the foreground color for the
+// top pixel and the background color for the bottom one. The frame is shrunk to fit above two
+// lines at the bottom: a status bar showing the last stats line, and a message line.
+//
+// The terminal is switched to raw mode so every key press arrives at once and goes through the
+// same keyBindings as the window. The arrow keys move the rectangle, ':' starts typing a
+// command, and q or ctrl-c quits.
+type terminalDisplay struct {
+	// FrameInterval is the shortest time between two presented frames, drawing is slow.
+	FrameInterval time.Duration
+
+	tty     *os.File
+	out     *bufio.Writer
+	restore string
+
+	columns     int
+	rows        int
+	sizeChecked time.Time
+	lastPresent time.Time
+
+	mutex   sync.Mutex
+	input   []InputEvent
+	status  string
+	message string
+	typing  bool
+	typed   string
+	quit    bool
+}
+
+// how many lines at the bottom of the terminal are not part of the picture
+const terminalStatusLines = 2
+
+// how often the terminal size is checked again, so that resizing the terminal resizes the picture
+const terminalSizeInterval = time.Second
+
+var arrowKeyCommands = map[byte]string{
+	'A': "rect up",
+	'B': "rect down",
+	'C': "rect right",
+	'D': "rect left",
+}
+
+// newTerminalDisplay takes over the terminal on tty until Close. It uses stty, so it needs a unix
+// like system.
+func newTerminalDisplay(tty *os.File, out io.Writer, frameInterval time.Duration) (*terminalDisplay, error) {
+	restore, err := stty(tty, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("can't read the terminal settings, is stdin a terminal? %s", err)
+	}
+	display := &terminalDisplay{
+		FrameInterval: frameInterval,
+		tty:           tty,
+		out:           bufio.NewWriter(out),
+		restore:       strings.TrimSpace(restore),
+	}
+	err = display.checkSize()
+	if err != nil {
+		return nil, err
+	}
+	_, err = stty(tty, "raw", "-echo")
+	if err != nil {
+		return nil, err
+	}
+
+	// clear the screen and hide the cursor
+	fmt.Fprint(display.out, "\x1b[2J\x1b[?25l")
+	go display.readKeys()
+	return display, nil
+}
+
+func stty(tty *os.File, arguments ...string) (string, error) {
+	command := exec.Command("stty", arguments...)
+	command.Stdin = tty
+	output, err := command.Output()
+	return string(output), err
+}
+
+func (display *terminalDisplay) checkSize() error {
+	size, err := stty(display.tty, "size")
+	if err != nil {
+		return err
+	}
+	var rows, columns int
+	_, err = fmt.Sscan(size, &rows, &columns)
+	if err != nil {
+		return fmt.Errorf("can't read the terminal size from '%s': %s", strings.TrimSpace(size), err)
+	}
+	if rows <= terminalStatusLines || columns < 1 {
+		return fmt.Errorf("the terminal is too small: %d rows, %d columns", rows, columns)
+	}
+	display.rows, display.columns = rows, columns
+	display.sizeChecked = time.Now()
+	return nil
+}
+
+// Write sets the status bar. It takes the stats lines the demo logs, and log output, so that
+// neither scribbles over the picture. The last line written is the one shown.
+func (display *terminalDisplay) Write(p []byte) (int, error) {
+	lines := strings.Split(strings.TrimRight(string(p), "\n"), "\n")
+	line := lines[len(lines)-1]
+
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	if strings.HasPrefix(line, "frame ") {
+		display.status = line
+	} else {
+		display.message = line
+	}
+	return len(p), nil
+}
+
+func (display *terminalDisplay) Present(rgba *image.RGBA) error {
+	wait := display.FrameInterval - time.Since(display.lastPresent)
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	display.lastPresent = time.Now()
+
+	if time.Since(display.sizeChecked) > terminalSizeInterval {
+		// if the size can't be read this time, keep the old one
+		display.checkSize()
+	}
+
+	// square pixels: two per cell vertically, and as many columns as there are rows of pixels
+	bounds := rgba.Rect
+	pixels := display.columns
+	if heightInPixels := (display.rows - terminalStatusLines) * 2; heightInPixels < pixels {
+		pixels = heightInPixels
+	}
+	cellRows := pixels / 2
+
+	out := display.out
+	fmt.Fprint(out, "\x1b[H")
+	var foreground, background color.RGBA
+	for row := 0; row < cellRows; row++ {
+		for column := 0; column < pixels; column++ {
+			top := averageColor(rgba, scaledRect(bounds, column, row*2, pixels))
+			bottom := averageColor(rgba, scaledRect(bounds, column, row*2+1, pixels))
+
+			// only send the colors that changed since the last cell
+			if column == 0 || top != foreground {
+				fmt.Fprintf(out, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+				foreground = top
+			}
+			if column == 0 || bottom != background {
+				fmt.Fprintf(out, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+				background = bottom
+			}
+			fmt.Fprint(out, "▀")
+		}
+		fmt.Fprint(out, "\x1b[0m\x1b[K\r\n")
+	}
+	// whatever is left over below the picture, after the terminal got taller
+	for row := cellRows; row < display.rows-terminalStatusLines; row++ {
+		fmt.Fprint(out, "\x1b[K\r\n")
+	}
+
+	display.mutex.Lock()
+	status, message := display.status, display.message
+	if display.typing {
+		message = ":" + display.typed
+	}
+	display.mutex.Unlock()
+
+	fmt.Fprintf(out, "\x1b[7m%s\x1b[0m\x1b[K\r\n", fitText(status, display.columns))
+	fmt.Fprintf(out, "%s\x1b[K", fitText(message, display.columns))
+	return out.Flush()
+}
+
+// scaledRect is the part of bounds covered by pixel x,y of a square picture pixels wide.
+func scaledRect(bounds image.Rectangle, x, y, pixels int) image.Rectangle {
+	return image.Rect(
+		bounds.Min.X+x*bounds.Dx()/pixels,
+		bounds.Min.Y+y*bounds.Dy()/pixels,
+		bounds.Min.X+(x+1)*bounds.Dx()/pixels,
+		bounds.Min.Y+(y+1)*bounds.Dy()/pixels,
+	)
+}
+
+// averageColor is the mean color of a rectangle, so thin features like the outline still show
+// up at a fraction of their strength when the picture is shrunk.
+func averageColor(rgba *image.RGBA, rect image.Rectangle) color.RGBA {
+	if rect.Empty() {
+		return rgba.RGBAAt(rect.Min.X, rect.Min.Y)
+	}
+	var r, g, b int
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			offset := rgba.PixOffset(x, y)
+			r += int(rgba.Pix[offset])
+			g += int(rgba.Pix[offset+1])
+			b += int(rgba.Pix[offset+2])
+		}
+	}
+	count := rect.Dx() * rect.Dy()
+	return color.RGBA{uint8(r / count), uint8(g / count), uint8(b / count), 0xff}
+}
+
+// fitText cuts text down to width characters.
+func fitText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	return string([]rune(text)[:width])
+}
+
+// readKeys turns bytes from the raw terminal into InputEvents until the terminal is closed.
+func (display *terminalDisplay) readKeys() {
+	reader := bufio.NewReader(display.tty)
+	for {
+		char, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+		if char == 0x1b && reader.Buffered() >= 2 {
+			// an escape sequence, like the arrow keys send
+			sequence, _ := reader.Peek(2)
+			if sequence[0] == '[' {
+				reader.Discard(2)
+				if command, has := arrowKeyCommands[sequence[1]]; has {
+					display.queue(InputEvent{Kind: InputCommand, Command: command})
+				}
+				continue
+			}
+		}
+		display.handleKey(char)
+	}
+}
+
+func (display *terminalDisplay) handleKey(char rune) {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+
+	if display.typing {
+		switch char {
+		case '\r', '\n':
+			display.typing = false
+			display.input = append(display.input, InputEvent{Kind: InputCommand, Command: display.typed})
+		case 0x1b, 0x03:
+			display.typing = false
+		case 0x7f, '\b':
+			if typed := []rune(display.typed); len(typed) > 0 {
+				display.typed = string(typed[:len(typed)-1])
+			}
+		default:
+			if char >= ' ' {
+				display.typed += string(char)
+			}
+		}
+		return
+	}
+
+	switch char {
+	case 'q', 0x03:
+		display.quit = true
+	case ':':
+		display.typing = true
+		display.typed = ""
+	default:
+		display.input = append(display.input, InputEvent{Kind: InputChar, Char: char})
+	}
+}
+
+func (display *terminalDisplay) queue(event InputEvent) {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	display.input = append(display.input, event)
+}
+
+func (display *terminalDisplay) PollInput() []InputEvent {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	input := display.input
+	display.input = nil
+	return input
+}
+
+func (display *terminalDisplay) ShouldClose() bool {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+	return display.quit
+}
+
+// Close puts the terminal back the way it was: cooked mode, the cursor visible, the screen clear.
+func (display *terminalDisplay) Close() error {
+	fmt.Fprint(display.out, "\x1b[0m\x1b[2J\x1b[H\x1b[?25h")
+	err := display.out.Flush()
+	_, restoreErr := stty(display.tty, display.restore)
+	if err == nil {
+		err = restoreErr
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QueryRect is a rectangle in world coordinates: fractions of the index input range, where 0 is
+// inputMin and 1 is inputMax on both axes. World coordinates don't depend on the curve bit width
+// or on the viewport, and float64 has enough precision to address single cells of a 64 bit curve.
+type QueryRect struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+}
+
+func (rect QueryRect) Width() float64 {
+	return rect.MaxX - rect.MinX
+}
+
+func (rect QueryRect) Height() float64 {
+	return rect.MaxY - rect.MinY
+}
+
+func (rect QueryRect) Empty() bool {
+	return rect.MinX >= rect.MaxX || rect.MinY >= rect.MaxY
+}
+
+func (rect QueryRect) Contains(x, y float64) bool {
+	return x >= rect.MinX && x < rect.MaxX && y >= rect.MinY && y < rect.MaxY
+}
+
+func (rect QueryRect) Add(dx, dy float64) QueryRect {
+	return QueryRect{rect.MinX + dx, rect.MinY + dy, rect.MaxX + dx, rect.MaxY + dy}
+}
+
+// Union returns the smallest rectangle containing both rect and other.
+func (rect QueryRect) Union(other QueryRect) QueryRect {
+	return QueryRect{
+		math.Min(rect.MinX, other.MinX),
+		math.Min(rect.MinY, other.MinY),
+		math.Max(rect.MaxX, other.MaxX),
+		math.Max(rect.MaxY, other.MaxY),
+	}
+}
+
+// Clamp returns the part of the rectangle inside the world, where the index accepts coordinates.
+func (rect QueryRect) Clamp() QueryRect {
+	return QueryRect{clampUnit(rect.MinX), clampUnit(rect.MinY), clampUnit(rect.MaxX), clampUnit(rect.MaxY)}
+}
+
+// KeepInside moves the rectangle back inside the world without changing its size, as far as it fits.
+func (rect QueryRect) KeepInside() QueryRect {
+	if rect.MaxX > 1 {
+		rect = rect.Add(1-rect.MaxX, 0)
+	}
+	if rect.MaxY > 1 {
+		rect = rect.Add(0, 1-rect.MaxY)
+	}
+	if rect.MinX < 0 {
+		rect = rect.Add(-rect.MinX, 0)
+	}
+	if rect.MinY < 0 {
+		rect = rect.Add(0, -rect.MinY)
+	}
+	return rect.Clamp()
+}
+
+func clampUnit(value float64) float64 {
+	return math.Min(1, math.Max(0, value))
+}
+
+// Canon returns the rectangle with min and max swapped where needed, like image.Rectangle.Canon.
+func (rect QueryRect) Canon() QueryRect {
+	if rect.MaxX < rect.MinX {
+		rect.MinX, rect.MaxX = rect.MaxX, rect.MinX
+	}
+	if rect.MaxY < rect.MinY {
+		rect.MinY, rect.MaxY = rect.MaxY, rect.MinY
+	}
+	return rect
+}
+
+// PixelRect is a rectangle in pixels of the unzoomed frame, the unit rectangle paths, the -rect
+// flag and the logs use. Fractional pixels are allowed so that zoomed-in queries can be written down.
+type PixelRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (rect PixelRect) ToQueryRect(size int) QueryRect {
+	return QueryRect{
+		MinX: rect.X / float64(size),
+		MinY: rect.Y / float64(size),
+		MaxX: (rect.X + rect.Width) / float64(size),
+		MaxY: (rect.Y + rect.Height) / float64(size),
+	}
+}
+
+func (rect QueryRect) ToPixelRect(size int) PixelRect {
+	return PixelRect{
+		X:      rect.MinX * float64(size),
+		Y:      rect.MinY * float64(size),
+		Width:  rect.Width() * float64(size),
+		Height: rect.Height() * float64(size),
+	}
+}
+
+// maxZoom is far enough for a single cell of a 64 bit curve to cover many pixels.
+const maxZoom = float64(1 << 40)
+
+// how much one notch of the scroll wheel zooms in
+const scrollZoomFactor = 1.25
+
+// Viewport is the part of the world that the main view shows. Zoom 1 shows the whole input range,
+// zoom 2 shows half of it on each axis around the center, and so on.
+type Viewport struct {
+	CenterX float64
+	CenterY float64
+	Zoom    float64
+}
+
+func DefaultViewport() Viewport {
+	return Viewport{CenterX: 0.5, CenterY: 0.5, Zoom: 1}
+}
+
+func (view Viewport) span() float64 {
+	return float64(1) / view.Zoom
+}
+
+// ScreenToWorld maps a position in frame pixels to world coordinates.
+func (view Viewport) ScreenToWorld(x, y float64, size int) (float64, float64) {
+	span := view.span()
+	return view.CenterX + (x/float64(size)-0.5)*span, view.CenterY + (y/float64(size)-0.5)*span
+}
+
+// WorldToScreen maps world coordinates to a position in frame pixels.
+func (view Viewport) WorldToScreen(x, y float64, size int) (float64, float64) {
+	zoom := view.Zoom * float64(size)
+	return (x-view.CenterX)*zoom + float64(size)/2, (y-view.CenterY)*zoom + float64(size)/2
+}
+
+// ScreenRect returns the rectangle in frame pixels, rounded to the nearest pixel.
+func (view Viewport) ScreenRect(rect QueryRect, size int) image.Rectangle {
+	minX, minY := view.WorldToScreen(rect.MinX, rect.MinY, size)
+	maxX, maxY := view.WorldToScreen(rect.MaxX, rect.MaxY, size)
+	return image.Rect(int(math.Round(minX)), int(math.Round(minY)), int(math.Round(maxX)), int(math.Round(maxY)))
+}
+
+// PointerToWorld converts a pointer event from frame pixels to world coordinates.
+func (view Viewport) PointerToWorld(event PointerEvent, size int) PointerEvent {
+	event.X, event.Y = view.ScreenToWorld(event.X, event.Y, size)
+	event.Radius = event.Radius * view.span() / float64(size)
+	return event
+}
+
+// ZoomAround zooms by factor while keeping the world point under the given frame pixel in place,
+// which is what the scroll wheel does.
+func (view *Viewport) ZoomAround(x, y, factor float64, size int) {
+	worldX, worldY := view.ScreenToWorld(x, y, size)
+	view.Zoom = math.Min(maxZoom, math.Max(1, view.Zoom*factor))
+	afterX, afterY := view.ScreenToWorld(x, y, size)
+	view.CenterX += worldX - afterX
+	view.CenterY += worldY - afterY
+	view.clamp()
+}
+
+// Pan moves the view by a fraction of what is currently visible.
+func (view *Viewport) Pan(dx, dy float64) {
+	view.CenterX += dx * view.span()
+	view.CenterY += dy * view.span()
+	view.clamp()
+}
+
+// clamp keeps the view inside the input range.
+func (view *Viewport) clamp() {
+	half := view.span() / 2
+	view.CenterX = math.Min(1-half, math.Max(half, view.CenterX))
+	view.CenterY = math.Min(1-half, math.Max(half, view.CenterY))
+}
+
+// parseFloats parses a comma separated list of exactly count finite numbers.
+func parseFloats(value string, count int, description string) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %s but got '%s'", description, value)
+	}
+	numbers := make([]float64, len(parts))
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected %s but got '%s': %s", description, value, err)
+		}
+		if math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, fmt.Errorf("expected %s but got '%s': %s is not a finite number", description, value, part)
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}